@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	bloomMagic   = "UBLOOM"
+	bloomVersion = 1
+)
+
+var (
+	ErrBloomFormat  = errors.New("bloom: not a bloom set snapshot")
+	ErrBloomVersion = errors.New("bloom: unsupported snapshot version")
+)
+
+type bloomFilter struct {
+	words []atomic.Uint64
+	count atomic.Uint64
+}
+
+type BloomSet[T comparable] struct {
+	generations []*bloomFilter
+	bits        uint64
+	hashes      uint32
+	expected    uint64
+	maxGens     int
+	mu          sync.RWMutex
+}
+
+type bloomHeader struct {
+	Magic       [6]byte
+	Version     uint16
+	Bits        uint64
+	Hashes      uint32
+	Expected    uint64
+	MaxGens     uint32
+	Generations uint32
+}
+
+func NewBloomSet[T comparable](expected int, fpRate float64) *BloomSet[T] {
+	if expected <= 0 {
+		panic("expected must be greater than 0")
+	} else if fpRate <= 0 || fpRate >= 1 {
+		panic("fpRate must be between 0 and 1")
+	}
+
+	bits := math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	words := uint64(math.Ceil(bits / 64))
+	hashes := max(1, uint32(math.Round(float64(words*64)/float64(expected)*math.Ln2)))
+
+	set := &BloomSet[T]{
+		bits:     words * 64,
+		hashes:   hashes,
+		expected: uint64(expected),
+		maxGens:  1,
+	}
+	set.generations = []*bloomFilter{set.newFilter()}
+
+	return set
+}
+
+func (b *BloomSet[T]) WithGenerations(generations int) *BloomSet[T] {
+	b.mu.Lock()
+	b.maxGens = max(1, generations)
+	if extra := len(b.generations) - b.maxGens; extra > 0 {
+		b.generations = slices.Delete(b.generations, 0, extra)
+	}
+	b.mu.Unlock()
+	return b
+}
+
+// RotateEvery does nothing for a non-positive interval; rotation then only
+// happens through Rotate or when a generation fills up.
+func (b *BloomSet[T]) RotateEvery(ctx context.Context, every time.Duration) *BloomSet[T] {
+	if every <= 0 {
+		return b
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				b.Rotate()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return b
+}
+
+func (b *BloomSet[T]) Rotate() {
+	b.mu.Lock()
+	b.rotate()
+	b.mu.Unlock()
+}
+
+func (b *BloomSet[T]) Add(id T) {
+	h1, h2 := bloomHash(id)
+
+	b.mu.RLock()
+	current := b.generations[len(b.generations)-1]
+	full := b.set(current, h1, h2) && b.maxGens > 1 && current.count.Load() >= b.expected
+	b.mu.RUnlock()
+
+	if full {
+		b.mu.Lock()
+		if b.generations[len(b.generations)-1] == current {
+			b.rotate()
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *BloomSet[T]) AddIfAbsent(id T) bool {
+	h1, h2 := bloomHash(id)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	exists := slices.ContainsFunc(b.generations, func(filter *bloomFilter) bool {
+		return b.test(filter, h1, h2)
+	})
+
+	current := b.generations[len(b.generations)-1]
+	if b.set(current, h1, h2) && b.maxGens > 1 && current.count.Load() >= b.expected {
+		b.rotate()
+	}
+
+	return !exists
+}
+
+func (b *BloomSet[T]) Exists(items ...T) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return slices.ContainsFunc(items, func(item T) bool {
+		h1, h2 := bloomHash(item)
+		return slices.ContainsFunc(b.generations, func(filter *bloomFilter) bool {
+			return b.test(filter, h1, h2)
+		})
+	})
+}
+
+// Len is an approximate total: it sums the insertions counted by every live
+// generation, so an ID added again after a rotation is counted more than once.
+func (b *BloomSet[T]) Len() int {
+	b.mu.RLock()
+	var count uint64
+	for _, filter := range b.generations {
+		count += filter.count.Load()
+	}
+	b.mu.RUnlock()
+	return int(count)
+}
+
+func (b *BloomSet[T]) Clear() {
+	b.mu.Lock()
+	b.generations = []*bloomFilter{b.newFilter()}
+	b.mu.Unlock()
+}
+
+func (b *BloomSet[T]) MarshalBinary() ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	header := bloomHeader{
+		Version:     bloomVersion,
+		Bits:        b.bits,
+		Hashes:      b.hashes,
+		Expected:    b.expected,
+		MaxGens:     uint32(b.maxGens),
+		Generations: uint32(len(b.generations)),
+	}
+	copy(header.Magic[:], bloomMagic)
+
+	data, err := binary.Append(make([]byte, 0, binary.Size(header)+len(b.generations)*int(b.bits/8+8)), binary.BigEndian, header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, filter := range b.generations {
+		data = binary.BigEndian.AppendUint64(data, filter.count.Load())
+		for i := range filter.words {
+			data = binary.BigEndian.AppendUint64(data, filter.words[i].Load())
+		}
+	}
+
+	return data, nil
+}
+
+func (b *BloomSet[T]) UnmarshalBinary(data []byte) error {
+	var header bloomHeader
+	read, err := binary.Decode(data, binary.BigEndian, &header)
+	if err != nil || string(header.Magic[:]) != bloomMagic {
+		return ErrBloomFormat
+	} else if header.Version != bloomVersion {
+		return fmt.Errorf("%w: %d (want %d)", ErrBloomVersion, header.Version, bloomVersion)
+	} else if header.Bits == 0 || header.Bits%64 != 0 || header.Hashes == 0 || header.Generations == 0 {
+		return ErrBloomFormat
+	}
+
+	// Dividing the payload keeps a crafted header from overflowing the size
+	// check; (words+1)*8 itself always fits since words <= 1<<58.
+	data = data[read:]
+	words := header.Bits / 64
+	perGen := (words + 1) * 8
+	if size := uint64(len(data)); size%perGen != 0 || size/perGen != uint64(header.Generations) {
+		return ErrBloomFormat
+	}
+
+	next := func() uint64 {
+		value := binary.BigEndian.Uint64(data)
+		data = data[8:]
+		return value
+	}
+
+	generations := make([]*bloomFilter, header.Generations)
+	for i := range generations {
+		filter := &bloomFilter{words: make([]atomic.Uint64, words)}
+		filter.count.Store(next())
+
+		for j := range filter.words {
+			filter.words[j].Store(next())
+		}
+
+		generations[i] = filter
+	}
+
+	b.mu.Lock()
+	b.bits = header.Bits
+	b.hashes = header.Hashes
+	b.expected = header.Expected
+	b.maxGens = max(1, int(header.MaxGens))
+	b.generations = generations
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *BloomSet[T]) newFilter() *bloomFilter {
+	return &bloomFilter{words: make([]atomic.Uint64, b.bits/64)}
+}
+
+func (b *BloomSet[T]) rotate() {
+	b.generations = append(b.generations, b.newFilter())
+	if len(b.generations) > b.maxGens {
+		b.generations = slices.Delete(b.generations, 0, len(b.generations)-b.maxGens)
+	}
+}
+
+func (b *BloomSet[T]) set(filter *bloomFilter, h1, h2 uint64) bool {
+	added := false
+	for i := range uint64(b.hashes) {
+		bit := (h1 + i*h2) % b.bits
+		mask := uint64(1) << (bit % 64)
+		if filter.words[bit/64].Or(mask)&mask == 0 {
+			added = true
+		}
+	}
+
+	if added {
+		filter.count.Add(1)
+	}
+	return added
+}
+
+func (b *BloomSet[T]) test(filter *bloomFilter, h1, h2 uint64) bool {
+	for i := range uint64(b.hashes) {
+		bit := (h1 + i*h2) % b.bits
+		if filter.words[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func bloomHash[T comparable](value T) (uint64, uint64) {
+	hasher := fnv.New64a()
+
+	switch v := any(value).(type) {
+	case string:
+		hasher.Write([]byte(v))
+	case int:
+		hasher.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+	case int64:
+		hasher.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+	case uint64:
+		hasher.Write(binary.BigEndian.AppendUint64(nil, v))
+	default:
+		hasher.Write(fmt.Append(nil, v))
+	}
+
+	h1 := hasher.Sum64()
+
+	h2 := h1 * 0x9E3779B97F4A7C15
+	h2 ^= h2 >> 31
+	return h1, h2 | 1
+}
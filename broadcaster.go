@@ -1,32 +1,169 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	OverflowDropNewest overflowPolicy = iota
+	OverflowDropOldest
+	OverflowBlock
+	OverflowDisconnect
+)
+
+const (
+	DefaultSubscribeBuffer = 10
+	DefaultBlockTimeout    = time.Second
+)
+
+var ErrBroadcasterClosed = errors.New("broadcaster closed")
+
+type overflowPolicy int
+
+type subscribeConfig struct {
+	name    string
+	buffer  int
+	policy  overflowPolicy
+	timeout time.Duration
+}
+
+type SubscribeOption func(*subscribeConfig)
+
+type subscriber[T any] struct {
+	ch      chan T
+	done    chan struct{}
+	config  subscribeConfig
+	dropped atomic.Uint64
+}
+
+type SubscriberStats struct {
+	Name    string `json:"name,omitempty"`
+	Policy  string `json:"policy"`
+	Buffer  int    `json:"buffer"`
+	Pending int    `json:"pending"`
+	Dropped uint64 `json:"dropped"`
+}
+
 type Broadcaster[T any] struct {
-	subscribers []chan T
+	subscribers []*subscriber[T]
+	closed      bool
+	replay      int
+	history     []T
 	mu          sync.RWMutex `json:"-"`
 }
 
+func (p overflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowBlock:
+		return "block"
+	case OverflowDisconnect:
+		return "disconnect"
+	}
+
+	return "unknown"
+}
+
+func WithBuffer(size int) SubscribeOption {
+	return func(config *subscribeConfig) {
+		config.buffer = max(0, size)
+	}
+}
+
+func WithOverflow(policy overflowPolicy, timeout ...time.Duration) SubscribeOption {
+	return func(config *subscribeConfig) {
+		config.policy = policy
+		if len(timeout) > 0 {
+			config.timeout = timeout[0]
+		}
+	}
+}
+
+func WithSubscriberName(name string) SubscribeOption {
+	return func(config *subscribeConfig) {
+		config.name = name
+	}
+}
+
 func NewBroadcaster[T any]() *Broadcaster[T] {
 	return &Broadcaster[T]{}
 }
 
-func (b *Broadcaster[T]) Subscribe() chan T {
-	ch := make(chan T, 10)
+func NewReplayBroadcaster[T any](size int) *Broadcaster[T] {
+	return &Broadcaster[T]{replay: max(0, size)}
+}
+
+func NewBehaviorBroadcaster[T any](initial ...T) *Broadcaster[T] {
+	b := &Broadcaster[T]{replay: 1}
+	if len(initial) > 0 {
+		b.history = []T{initial[len(initial)-1]}
+	}
+	return b
+}
+
+func (b *Broadcaster[T]) Subscribe(options ...SubscribeOption) chan T {
+	return b.subscribe(options).ch
+}
+
+func (b *Broadcaster[T]) SubscribeCtx(ctx context.Context, options ...SubscribeOption) chan T {
+	return b.subscribeUntil(ctx.Done(), options)
+}
+
+func (b *Broadcaster[T]) SubscribeContext(ctx *Context, options ...SubscribeOption) chan T {
+	return b.subscribeUntil(ctx.C(), options)
+}
+
+func (b *Broadcaster[T]) subscribeUntil(done <-chan struct{}, options []SubscribeOption) chan T {
+	sub := b.subscribe(options)
+
+	go func() {
+		select {
+		case <-done:
+			b.Unsubscribe(sub.ch)
+		case <-sub.done:
+		}
+	}()
+
+	return sub.ch
+}
+
+func (b *Broadcaster[T]) subscribe(options []SubscribeOption) *subscriber[T] {
+	config := subscribeConfig{buffer: DefaultSubscribeBuffer, timeout: DefaultBlockTimeout}
+	for _, option := range options {
+		option(&config)
+	}
+
 	b.mu.Lock()
-	b.subscribers = append(b.subscribers, ch)
-	b.mu.Unlock()
-	return ch
+	defer b.mu.Unlock()
+
+	// Replayed messages are queued under the same lock Broadcast takes in
+	// replay mode, so nothing is lost or repeated at the handover.
+	sub := &subscriber[T]{ch: make(chan T, config.buffer+len(b.history)), done: make(chan struct{}), config: config}
+	for _, msg := range b.history {
+		sub.ch <- msg
+	}
+
+	if b.closed {
+		sub.close()
+	} else {
+		b.subscribers = append(b.subscribers, sub)
+	}
+	return sub
 }
 
 func (b *Broadcaster[T]) Unsubscribe(ch chan T) {
 	b.mu.Lock()
-	b.subscribers = slices.DeleteFunc(b.subscribers, func(c chan T) bool {
-		if c == ch {
-			close(c)
+	b.subscribers = slices.DeleteFunc(b.subscribers, func(sub *subscriber[T]) bool {
+		if sub.ch == ch {
+			sub.close()
 			return true
 		}
 		return false
@@ -34,13 +171,154 @@ func (b *Broadcaster[T]) Unsubscribe(ch chan T) {
 	b.mu.Unlock()
 }
 
-func (b *Broadcaster[T]) Broadcast(msg T) {
+func (b *Broadcaster[T]) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, sub := range b.subscribers {
+			sub.close()
+		}
+		b.subscribers = nil
+	}
+	b.mu.Unlock()
+}
+
+func (b *Broadcaster[T]) Closed() bool {
 	b.mu.RLock()
-	for _, ch := range b.subscribers {
+	closed := b.closed
+	b.mu.RUnlock()
+	return closed
+}
+
+func (b *Broadcaster[T]) Broadcast(msg T) error {
+	var slow []chan T
+
+	lock, unlock := b.mu.RLock, b.mu.RUnlock
+	if b.replay > 0 {
+		lock, unlock = b.mu.Lock, b.mu.Unlock
+	}
+
+	lock()
+	if b.closed {
+		unlock()
+		return ErrBroadcasterClosed
+	}
+
+	if b.replay > 0 {
+		b.history = append(b.history, msg)
+		if len(b.history) > b.replay {
+			b.history = slices.Delete(b.history, 0, len(b.history)-b.replay)
+		}
+	}
+
+	for _, sub := range b.subscribers {
+		if !sub.deliver(msg) {
+			slow = append(slow, sub.ch)
+		}
+	}
+	unlock()
+
+	for _, ch := range slow {
+		b.Unsubscribe(ch)
+	}
+
+	return nil
+}
+
+func (b *Broadcaster[T]) Last() (T, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.history) == 0 {
+		var empty T
+		return empty, false
+	}
+
+	return b.history[len(b.history)-1], true
+}
+
+func (b *Broadcaster[T]) History() []T {
+	b.mu.RLock()
+	history := slices.Clone(b.history)
+	b.mu.RUnlock()
+	return history
+}
+
+func (b *Broadcaster[T]) Dropped(ch chan T) uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if sub.ch == ch {
+			return sub.dropped.Load()
+		}
+	}
+
+	return 0
+}
+
+func (b *Broadcaster[T]) Stats() []SubscriberStats {
+	b.mu.RLock()
+	stats := make([]SubscriberStats, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		stats = append(stats, SubscriberStats{
+			Name:    sub.config.name,
+			Policy:  sub.config.policy.String(),
+			Buffer:  cap(sub.ch),
+			Pending: len(sub.ch),
+			Dropped: sub.dropped.Load(),
+		})
+	}
+	b.mu.RUnlock()
+	return stats
+}
+
+func (b *Broadcaster[T]) Len() int {
+	b.mu.RLock()
+	length := len(b.subscribers)
+	b.mu.RUnlock()
+	return length
+}
+
+func (sub *subscriber[T]) close() {
+	close(sub.ch)
+	close(sub.done)
+}
+
+func (sub *subscriber[T]) deliver(msg T) bool {
+	select {
+	case sub.ch <- msg:
+		return true
+	default:
+	}
+
+	switch sub.config.policy {
+	case OverflowDropOldest:
 		select {
-		case ch <- msg:
+		case <-sub.ch:
+			sub.dropped.Add(1)
+		default:
+		}
+
+		select {
+		case sub.ch <- msg:
+			return true
 		default:
 		}
+	case OverflowBlock:
+		timer := time.NewTimer(sub.config.timeout)
+		defer timer.Stop()
+
+		select {
+		case sub.ch <- msg:
+			return true
+		case <-timer.C:
+		}
+	case OverflowDisconnect:
+		sub.dropped.Add(1)
+		return false
 	}
-	b.mu.RUnlock()
+
+	sub.dropped.Add(1)
+	return true
 }
@@ -0,0 +1,424 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHeartbeat    = 15 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+const (
+	streamSSE streamMode = iota
+	streamWebSocket
+)
+
+const (
+	wsGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsOpText        = 0x1
+	wsOpBinary      = 0x2
+	wsOpClose       = 0x8
+	wsOpPing        = 0x9
+	wsOpPong        = 0xA
+	wsMaxControlLen = 125
+)
+
+var ErrWebSocketProtocol = errors.New("websocket: protocol error")
+
+type streamMode int
+
+type StreamHandler[T any] struct {
+	broadcaster  *Broadcaster[T]
+	mode         streamMode
+	encoder      func(T) ([]byte, error)
+	event        string
+	binary       bool
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+	checkOrigin  func(*http.Request) bool
+	options      []SubscribeOption
+}
+
+type wsConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+func NewSSEHandler[T any](broadcaster *Broadcaster[T]) *StreamHandler[T] {
+	return newStreamHandler(broadcaster, streamSSE)
+}
+
+func NewWebSocketHandler[T any](broadcaster *Broadcaster[T]) *StreamHandler[T] {
+	return newStreamHandler(broadcaster, streamWebSocket)
+}
+
+func newStreamHandler[T any](broadcaster *Broadcaster[T], mode streamMode) *StreamHandler[T] {
+	return &StreamHandler[T]{
+		broadcaster: broadcaster,
+		mode:        mode,
+		encoder: func(v T) ([]byte, error) {
+			return json.Marshal(v)
+		},
+		heartbeat:    DefaultHeartbeat,
+		writeTimeout: DefaultWriteTimeout,
+		checkOrigin:  sameOrigin,
+	}
+}
+
+func (h *StreamHandler[T]) WithEncoder(encoder func(T) ([]byte, error)) *StreamHandler[T] {
+	h.encoder = encoder
+	return h
+}
+
+func (h *StreamHandler[T]) WithHeartbeat(interval time.Duration) *StreamHandler[T] {
+	h.heartbeat = interval
+	return h
+}
+
+func (h *StreamHandler[T]) WithWriteTimeout(timeout time.Duration) *StreamHandler[T] {
+	h.writeTimeout = timeout
+	return h
+}
+
+func (h *StreamHandler[T]) WithSubscribeOptions(options ...SubscribeOption) *StreamHandler[T] {
+	h.options = options
+	return h
+}
+
+// WithCheckOrigin replaces the same-host check WebSocket upgrades go through;
+// passing nil restores it.
+func (h *StreamHandler[T]) WithCheckOrigin(check func(*http.Request) bool) *StreamHandler[T] {
+	if check == nil {
+		check = sameOrigin
+	}
+
+	h.checkOrigin = check
+	return h
+}
+
+func (h *StreamHandler[T]) WithEvent(event string) *StreamHandler[T] {
+	h.event = event
+	return h
+}
+
+func (h *StreamHandler[T]) WithBinary() *StreamHandler[T] {
+	h.binary = true
+	return h
+}
+
+func (h *StreamHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch h.mode {
+	case streamSSE:
+		h.serveSSE(w, r)
+	case streamWebSocket:
+		h.serveWebSocket(w, r)
+	}
+}
+
+func (h *StreamHandler[T]) heartbeatC() (<-chan time.Time, func()) {
+	if h.heartbeat <= 0 {
+		return nil, func() {}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	return ticker.C, ticker.Stop
+}
+
+func (h *StreamHandler[T]) serveSSE(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	ch := h.broadcaster.Subscribe(h.options...)
+	defer h.broadcaster.Unsubscribe(ch)
+
+	heartbeat, stop := h.heartbeatC()
+	defer stop()
+
+	write := func(data []byte) bool {
+		if h.writeTimeout > 0 {
+			controller.SetWriteDeadline(time.Now().Add(h.writeTimeout))
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			data, err := h.encoder(msg)
+			if err != nil {
+				continue
+			}
+
+			if !write(formatSSE(h.event, data)) {
+				return
+			}
+		case <-heartbeat:
+			if !write([]byte(": heartbeat\n\n")) {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func formatSSE(event string, data []byte) []byte {
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: " + event + "\n")
+	}
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	for line := range bytes.SplitSeq(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func (h *StreamHandler[T]) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	} else if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	} else if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "invalid websocket key", http.StatusBadRequest)
+		return
+	} else if !h.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	ws := &wsConn{conn: conn, reader: rw.Reader, timeout: h.writeTimeout}
+	accept := sha1.Sum([]byte(key + wsGUID))
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"
+
+	if ws.write([]byte(response)) != nil {
+		return
+	}
+
+	ch := h.broadcaster.Subscribe(h.options...)
+	defer h.broadcaster.Unsubscribe(ch)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		ws.readLoop()
+	}()
+
+	heartbeat, stop := h.heartbeatC()
+	defer stop()
+
+	opcode := byte(wsOpText)
+	if h.binary {
+		opcode = wsOpBinary
+	}
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				ws.writeFrame(wsOpClose, binary.BigEndian.AppendUint16(nil, 1001))
+				return
+			}
+
+			data, err := h.encoder(msg)
+			if err != nil {
+				continue
+			}
+
+			if ws.writeFrame(opcode, data) != nil {
+				return
+			}
+		case <-heartbeat:
+			if ws.writeFrame(wsOpPing, nil) != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (ws *wsConn) write(data []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.timeout > 0 {
+		ws.conn.SetWriteDeadline(time.Now().Add(ws.timeout))
+	}
+
+	_, err := ws.conn.Write(data)
+	return err
+}
+
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+
+	switch length := len(payload); {
+	case length <= wsMaxControlLen:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	return ws.write(append(frame, payload...))
+}
+
+// Client messages are only read to answer pings and to notice when the peer
+// goes away; their payloads are discarded.
+func (ws *wsConn) readLoop() {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if ws.writeFrame(wsOpPong, payload) != nil {
+				return
+			}
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, payload[:min(len(payload), 2)])
+			return
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := header[0] & 0x0F
+	control := opcode&0x8 != 0
+	if header[1]&0x80 == 0 {
+		return 0, nil, ErrWebSocketProtocol
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	if length > math.MaxInt64 || (control && (length > wsMaxControlLen || header[0]&0x80 == 0)) {
+		return 0, nil, ErrWebSocketProtocol
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	if !control {
+		_, err := io.CopyN(io.Discard, ws.reader, int64(length))
+		return opcode, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+// sameOrigin accepts requests without an Origin header, since browsers always
+// send one, and those whose Origin names the host the request was made to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
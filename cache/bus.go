@@ -0,0 +1,411 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Azizi-X/utils"
+)
+
+const (
+	OpRemove invalidationOp = iota
+	OpInvalidateTag
+	OpClear
+)
+
+const (
+	DefaultBusBuffer = 1024
+	busWriteTimeout  = 5 * time.Second
+)
+
+var (
+	ErrBusClosed  = errors.New("cache: invalidation bus closed")
+	ErrBusUnnamed = errors.New("cache: invalidation bus requires a named cache")
+	ErrBusDropped = errors.New("cache: invalidation subscriber fell behind, cache cleared")
+)
+
+type invalidationOp int
+
+type Invalidation struct {
+	Origin string          `json:"origin"`
+	Cache  string          `json:"cache"`
+	Op     invalidationOp  `json:"op"`
+	Key    json.RawMessage `json:"key,omitempty"`
+	Tag    string          `json:"tag,omitempty"`
+}
+
+type InvalidationTransport interface {
+	Publish(msg Invalidation) error
+	Subscribe() (<-chan Invalidation, func(), error)
+}
+
+type cacheBus struct {
+	transport InvalidationTransport
+	origin    string
+	onError   func(error)
+	cancel    func()
+	closed    bool
+	mu        sync.Mutex
+}
+
+// The cache name doubles as the channel name on the transport, so unnamed
+// caches cannot be attached without applying each other's operations.
+func (c *Cache[K, V]) AttachBus(transport InvalidationTransport, onError ...func(error)) error {
+	if c.name == "" {
+		return ErrBusUnnamed
+	}
+
+	msgs, cancel, err := transport.Subscribe()
+	if err != nil {
+		return err
+	}
+
+	bus := &cacheBus{transport: transport, origin: newOrigin(), cancel: cancel}
+	if len(onError) > 0 {
+		bus.onError = onError[0]
+	}
+
+	c.mu.Lock()
+	old := c.bus
+	c.bus = bus
+	c.mu.Unlock()
+
+	if old != nil {
+		old.close()
+	}
+
+	go c.listen(bus, msgs)
+
+	return nil
+}
+
+func (c *Cache[K, V]) DetachBus() {
+	c.mu.Lock()
+	bus := c.bus
+	c.bus = nil
+	c.mu.Unlock()
+
+	if bus != nil {
+		bus.close()
+	}
+}
+
+func (c *Cache[K, V]) listen(bus *cacheBus, msgs <-chan Invalidation) {
+	var done <-chan struct{}
+	if c.Ctx != nil {
+		done = c.Ctx.Done()
+	}
+
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				if msgs = c.resync(bus); msgs == nil {
+					return
+				}
+				continue
+			}
+
+			if msg.Origin != bus.origin && msg.Cache == c.name {
+				c.apply(msg)
+			}
+		case <-done:
+			bus.close()
+			return
+		}
+	}
+}
+
+// Transports drop subscribers that fall behind instead of losing messages
+// silently. Whatever was missed is unknown, so the cache is cleared after
+// subscribing again.
+func (c *Cache[K, V]) resync(bus *cacheBus) <-chan Invalidation {
+	bus.mu.Lock()
+	if bus.closed {
+		bus.mu.Unlock()
+		return nil
+	}
+
+	msgs, cancel, err := bus.transport.Subscribe()
+	if err != nil {
+		bus.closed = true
+	} else {
+		bus.cancel = cancel
+	}
+	bus.mu.Unlock()
+
+	c.stats.busErrors.Add(1)
+
+	if err != nil {
+		bus.report(err)
+		return nil
+	}
+
+	c.clearLocal()
+	bus.report(ErrBusDropped)
+
+	return msgs
+}
+
+func (c *Cache[K, V]) apply(msg Invalidation) {
+	switch msg.Op {
+	case OpRemove:
+		var key K
+		if err := json.Unmarshal(msg.Key, &key); err == nil {
+			c.removeLocal(key)
+		}
+	case OpInvalidateTag:
+		c.invalidateTagLocal(msg.Tag)
+	case OpClear:
+		c.clearLocal()
+	}
+}
+
+func (c *Cache[K, V]) publish(msg Invalidation) {
+	c.mu.RLock()
+	bus := c.bus
+	c.mu.RUnlock()
+
+	if bus == nil {
+		return
+	}
+
+	msg.Origin = bus.origin
+	msg.Cache = c.name
+
+	if err := bus.transport.Publish(msg); err != nil {
+		c.stats.busErrors.Add(1)
+		bus.report(err)
+	}
+}
+
+func (c *Cache[K, V]) publishKey(key K) {
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return
+	}
+
+	c.publish(Invalidation{Op: OpRemove, Key: raw})
+}
+
+func (bus *cacheBus) close() {
+	bus.mu.Lock()
+	if !bus.closed {
+		bus.closed = true
+		bus.cancel()
+	}
+	bus.mu.Unlock()
+}
+
+func (bus *cacheBus) report(err error) {
+	if bus.onError != nil {
+		bus.onError(err)
+	}
+}
+
+func subscribeTransport(broadcaster *utils.Broadcaster[Invalidation]) (<-chan Invalidation, func(), error) {
+	if broadcaster.Closed() {
+		return nil, nil, ErrBusClosed
+	}
+
+	ch := broadcaster.Subscribe(utils.WithBuffer(DefaultBusBuffer), utils.WithOverflow(utils.OverflowDisconnect))
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { broadcaster.Unsubscribe(ch) })
+	}, nil
+}
+
+func newOrigin() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+type BroadcastTransport struct {
+	broadcaster *utils.Broadcaster[Invalidation]
+}
+
+func NewBroadcastTransport(broadcaster *utils.Broadcaster[Invalidation]) *BroadcastTransport {
+	if broadcaster == nil {
+		broadcaster = utils.NewBroadcaster[Invalidation]()
+	}
+
+	return &BroadcastTransport{broadcaster: broadcaster}
+}
+
+func (t *BroadcastTransport) Publish(msg Invalidation) error {
+	return t.broadcaster.Broadcast(msg)
+}
+
+func (t *BroadcastTransport) Subscribe() (<-chan Invalidation, func(), error) {
+	return subscribeTransport(t.broadcaster)
+}
+
+type SocketBus struct {
+	listener net.Listener
+	conns    map[net.Conn]*sync.Mutex
+	mu       sync.Mutex
+	closed   bool
+}
+
+func ListenSocketBus(network, address string) (*SocketBus, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := &SocketBus{listener: listener, conns: map[net.Conn]*sync.Mutex{}}
+	go bus.accept()
+
+	return bus, nil
+}
+
+func (s *SocketBus) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *SocketBus) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = map[net.Conn]*sync.Mutex{}
+	s.mu.Unlock()
+
+	return s.listener.Close()
+}
+
+func (s *SocketBus) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = &sync.Mutex{}
+		s.mu.Unlock()
+
+		go s.relay(conn)
+	}
+}
+
+func (s *SocketBus) relay(from net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, from)
+		s.mu.Unlock()
+		from.Close()
+	}()
+
+	scanner := bufio.NewScanner(from)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	for scanner.Scan() {
+		line := append(scanner.Bytes(), '\n')
+
+		// Peers are written outside s.mu so one stalled connection cannot hold
+		// up the other relays or accept; the per-conn mutex orders the writes.
+		s.mu.Lock()
+		peers := maps.Clone(s.conns)
+		s.mu.Unlock()
+
+		for conn, mu := range peers {
+			if conn == from {
+				continue
+			}
+
+			mu.Lock()
+			conn.SetWriteDeadline(time.Now().Add(busWriteTimeout))
+			if _, err := conn.Write(line); err != nil {
+				conn.Close()
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+type SocketTransport struct {
+	conn        net.Conn
+	broadcaster *utils.Broadcaster[Invalidation]
+	mu          sync.Mutex
+}
+
+func DialSocketTransport(network, address string) (*SocketTransport, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &SocketTransport{conn: conn, broadcaster: utils.NewBroadcaster[Invalidation]()}
+	go transport.read(conn)
+
+	return transport, nil
+}
+
+func (t *SocketTransport) Publish(msg Invalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return ErrBusClosed
+	}
+
+	t.conn.SetWriteDeadline(time.Now().Add(busWriteTimeout))
+	_, err = t.conn.Write(append(data, '\n'))
+	return err
+}
+
+func (t *SocketTransport) Subscribe() (<-chan Invalidation, func(), error) {
+	return subscribeTransport(t.broadcaster)
+}
+
+func (t *SocketTransport) Close() error {
+	t.mu.Lock()
+	conn := t.conn
+	t.conn = nil
+	t.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	t.broadcaster.Close()
+	return conn.Close()
+}
+
+func (t *SocketTransport) read(conn net.Conn) {
+	// A dead connection closes the transport so attached caches learn that
+	// invalidations stopped arriving.
+	defer t.Close()
+
+	decoder := json.NewDecoder(conn)
+
+	for {
+		var msg Invalidation
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+
+		t.broadcaster.Broadcast(msg)
+	}
+}
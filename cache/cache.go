@@ -6,6 +6,7 @@ import (
 	"errors"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,23 +25,44 @@ const (
 	HalfDayDuration   = 12 * time.Hour
 	ShortDuration     = 5 * time.Minute
 	TenMinuteDuration = 10 * time.Minute
+
+	DefaultSweepInterval = 60 * time.Second
 )
 
 var (
 	ErrCacheNotFound = errors.New("cache not found")
 	ErrCacheExpired  = errors.New("cache expired")
 
-	keeper = NewKeeper()
+	keeper     = NewKeeper()
+	keeperOnce sync.Once
+	cacheIDs   atomic.Uint64
 )
 
 type cacheOption int
 type cacheLimit = int
 
 type Cache[K comparable, T any] struct {
-	Items map[K]cacheItem[T]
-	mu    sync.RWMutex
-	Ctx   context.Context
-	Limit int
+	Items      map[K]cacheItem[T]
+	mu         sync.RWMutex
+	Ctx        context.Context
+	Limit      int
+	loads      map[K]*loadCall[T]
+	refreshing map[K]struct{}
+	loadMu     sync.Mutex
+	policy     evictionPolicy
+	evictor    evictor[K]
+	onEvict    func(key K, value T, reason evictReason)
+	pending    []evicted[K, T]
+	name       string
+	id         uint64
+	stats      cacheStats
+	refresher  *refresher[K, T]
+	tags       map[string]map[K]struct{}
+	sizer      func(key K, value T) int
+	maxBytes   int
+	bytes      int
+	store      Store[K, T]
+	bus        *cacheBus
 }
 
 type CacheKeeper struct {
@@ -53,6 +75,8 @@ type cacheItem[T any] struct {
 	Expires  time.Time
 	Duration time.Duration
 	Value    T
+	Tags     []string
+	size     int
 }
 
 type cacheInter interface {
@@ -61,6 +85,10 @@ type cacheInter interface {
 	Check()
 }
 
+type statsInter interface {
+	Stats() CacheStats
+}
+
 type keeperInter interface {
 	Add(cache cacheInter)
 	GetItems() [][][]byte
@@ -70,10 +98,39 @@ func NewKeeper() *CacheKeeper {
 	return &CacheKeeper{}
 }
 
+func StartKeeper(ctx context.Context, interval time.Duration) *CacheKeeper {
+	ck := NewKeeper()
+	go ck.Run(ctx, interval)
+	return ck
+}
+
+func DefaultKeeper() *CacheKeeper {
+	return keeper
+}
+
 func (ck *CacheKeeper) Add(cache cacheInter) {
 	ck.mu.Lock()
 	ck.Caches = append(ck.Caches, cache)
 	ck.mu.Unlock()
+
+	// The default keeper only starts sweeping once a cache registers with it,
+	// so importing the package or using detached caches starts no goroutine.
+	if ck == keeper {
+		keeperOnce.Do(func() {
+			go keeper.Run(context.Background(), DefaultSweepInterval)
+		})
+	}
+}
+
+func (ck *CacheKeeper) Remove(cache cacheInter) bool {
+	ck.mu.Lock()
+	length := len(ck.Caches)
+	ck.Caches = slices.DeleteFunc(ck.Caches, func(c cacheInter) bool {
+		return c == cache
+	})
+	removed := len(ck.Caches) != length
+	ck.mu.Unlock()
+	return removed
 }
 
 func (ck *CacheKeeper) GetItems() (items [][][]byte) {
@@ -86,43 +143,139 @@ func (ck *CacheKeeper) GetItems() (items [][][]byte) {
 	return
 }
 
-func (ck *CacheKeeper) _cleanup() {
-	ticker := time.NewTicker(60 * time.Second)
+func (ck *CacheKeeper) Stats() (stats []CacheStats) {
+	ck.mu.Lock()
+	caches := slices.Clone(ck.Caches)
+	ck.mu.Unlock()
 
-	for range ticker.C {
-		ck.mu.Lock()
-		ck.Caches = slices.DeleteFunc(ck.Caches, func(cache cacheInter) bool {
-			ctx := cache.C()
-			return ctx != nil && ctx.Err() != nil
-		})
-		caches := slices.Clone(ck.Caches)
-		ck.mu.Unlock()
+	for _, cache := range caches {
+		if cache, ok := cache.(statsInter); ok {
+			stats = append(stats, cache.Stats())
+		}
+	}
 
-		for _, cache := range caches {
-			cache.Check()
+	return
+}
+
+func (ck *CacheKeeper) Sweep() {
+	ck.mu.Lock()
+	ck.Caches = slices.DeleteFunc(ck.Caches, func(cache cacheInter) bool {
+		ctx := cache.C()
+		return ctx != nil && ctx.Err() != nil
+	})
+	caches := slices.Clone(ck.Caches)
+	ck.mu.Unlock()
+
+	for _, cache := range caches {
+		cache.Check()
+	}
+}
+
+func (ck *CacheKeeper) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ck.Sweep()
+		case <-ctx.Done():
+			return
 		}
 	}
 }
 
+func newCache[K comparable, V any](ctx context.Context, name string) *Cache[K, V] {
+	return &Cache[K, V]{Items: map[K]cacheItem[V]{}, Ctx: ctx, Limit: DefaultCacheLimit, name: name, id: cacheIDs.Add(1)}
+}
+
 func NewCache[K comparable, V any](ctx context.Context) *Cache[K, V] {
-	c := &Cache[K, V]{Items: map[K]cacheItem[V]{}, Ctx: ctx, Limit: DefaultCacheLimit}
+	c := newCache[K, V](ctx, "")
+
+	keeper.Add(c)
+
+	return c
+}
+
+func NewNamedCache[K comparable, V any](ctx context.Context, name string) *Cache[K, V] {
+	c := newCache[K, V](ctx, name)
 
 	keeper.Add(c)
 
 	return c
 }
 
+func NewDetachedCache[K comparable, V any](ctx context.Context, name ...string) *Cache[K, V] {
+	var cacheName string
+	if len(name) > 0 {
+		cacheName = name[0]
+	}
+
+	return newCache[K, V](ctx, cacheName)
+}
+
 func (c *Cache[K, V]) C() context.Context {
 	return c.Ctx
 }
 
+func (c *Cache[K, V]) Name() string {
+	return c.name
+}
+
 func (c *Cache[K, V]) SetLimit(limit int) *Cache[K, V] {
+	c.mu.Lock()
 	c.Limit = limit
+	c.enforceLimitUnsafe()
+	c.unlock()
 	return c
 }
 
 func (c *Cache[K, V]) Nolimit() *Cache[K, V] {
-	c.Limit = NoLimit
+	return c.SetLimit(NoLimit)
+}
+
+func (c *Cache[K, V]) SetPolicy(policy evictionPolicy) *Cache[K, V] {
+	c.mu.Lock()
+	c.policy = policy
+	c.evictor = newEvictor[K](policy)
+	if c.evictor != nil {
+		for key := range c.Items {
+			c.evictor.insert(key)
+		}
+	}
+	c.enforceLimitUnsafe()
+	c.unlock()
+	return c
+}
+
+func (c *Cache[K, V]) SetSizer(fn func(key K, value V) int, maxBytes int) *Cache[K, V] {
+	c.mu.Lock()
+	c.sizer = fn
+	c.maxBytes = maxBytes
+	c.bytes = 0
+
+	for key, item := range c.Items {
+		item.size = 0
+		if fn != nil {
+			item.size = fn(key, item.Value)
+		}
+		c.bytes += item.size
+		c.Items[key] = item
+	}
+
+	c.enforceLimitUnsafe()
+	c.unlock()
+	return c
+}
+
+func (c *Cache[K, V]) OnEvict(fn func(key K, value V, reason evictReason)) *Cache[K, V] {
+	c.mu.Lock()
+	c.onEvict = fn
+	c.mu.Unlock()
 	return c
 }
 
@@ -137,19 +290,40 @@ func (c *Cache[K, V]) Exists(key K, options ...cacheOption) bool {
 }
 
 func (c *Cache[K, V]) UniqueSet(key K, data V, expires time.Duration, options ...cacheOption) bool {
-	c.mu.Lock()
-	_, already_set := c.getSetUnsafe(key, data, expires, options...)
-	c.mu.Unlock()
+	_, already_set := c.getSet(key, data, expires, options...)
 	return !already_set
 }
 
 func (c *Cache[K, V]) GetSet(key K, data V, expires time.Duration, options ...cacheOption) *V {
-	c.mu.Lock()
-	v, _ := c.getSetUnsafe(key, data, expires, options...)
-	c.mu.Unlock()
+	v, _ := c.getSet(key, data, expires, options...)
 	return v
 }
 
+func (c *Cache[K, V]) getSet(key K, data V, expires time.Duration, options ...cacheOption) (*V, bool) {
+	c.mu.RLock()
+	item, ok := c.Items[key]
+	store := c.store
+	c.mu.RUnlock()
+
+	// A key held only by L2 is promoted instead of being overwritten.
+	if store != nil && (!ok || !liveItem(item, time.Now())) {
+		if v, ok := c.promote(store, key); ok {
+			return v, true
+		}
+	}
+
+	c.mu.Lock()
+	v, already_set := c.getSetUnsafe(key, data, expires, options...)
+	store, tags := c.store, c.tagsUnsafe(key)
+	c.unlock()
+
+	if v != nil && !already_set {
+		c.writeThrough(store, key, data, tags, expires)
+	}
+
+	return v, already_set
+}
+
 func (c *Cache[K, V]) getSetUnsafe(key K, data V, expires time.Duration, options ...cacheOption) (*V, bool) {
 	v, err := c.getUnsafe(key, options...)
 	if v != nil && err != nil {
@@ -173,7 +347,7 @@ func (c *Cache[K, V]) Get(key K, options ...cacheOption) *V {
 }
 
 func (c *Cache[K, V]) GetErr(key K, options ...cacheOption) (*V, error) {
-	full_mu := slices.Contains(options, ResetTimer)
+	full_mu := slices.Contains(options, ResetTimer) || slices.Contains(options, ResetTimerOnErr)
 
 	if full_mu {
 		c.mu.Lock()
@@ -182,6 +356,7 @@ func (c *Cache[K, V]) GetErr(key K, options ...cacheOption) (*V, error) {
 	}
 
 	item, err := c.getUnsafe(key, options...)
+	refresher, store := c.refresher, c.store
 
 	if full_mu {
 		c.mu.Unlock()
@@ -190,11 +365,20 @@ func (c *Cache[K, V]) GetErr(key K, options ...cacheOption) (*V, error) {
 	}
 
 	if item == nil && err != nil {
+		if store != nil {
+			if v, ok := c.promote(store, key); ok {
+				return v, nil
+			}
+		}
 		return nil, err
 	} else if err != nil {
 		return &item.Value, err
 	}
 
+	if refresher != nil {
+		c.maybeRefresh(refresher, key, item)
+	}
+
 	return &item.Value, nil
 }
 
@@ -205,7 +389,12 @@ func (c *Cache[K, V]) Set(key K, data V, expires ...time.Duration) error {
 func (c *Cache[K, V]) SetErr(key K, data *V, err error, expires ...time.Duration) error {
 	c.mu.Lock()
 	c.setUnsafe(key, data, err, expires...)
-	c.mu.Unlock()
+	store, tags := c.store, c.tagsUnsafe(key)
+	c.unlock()
+
+	if err == nil && data != nil {
+		return c.writeThrough(store, key, *data, tags, expires...)
+	}
 
 	return err
 }
@@ -213,23 +402,46 @@ func (c *Cache[K, V]) SetErr(key K, data *V, err error, expires ...time.Duration
 func (c *Cache[K, V]) getUnsafe(key K, options ...cacheOption) (*cacheItem[V], error) {
 	item, ok := c.Items[key]
 	if !ok {
+		c.stats.misses.Add(1)
 		return nil, ErrCacheNotFound
 	}
 
 	if item.Duration != NoExpire && item.Expires.Before(time.Now()) {
-		return nil, ErrCacheExpired
+		if !c.staleUnsafe(item, time.Now()) {
+			c.stats.expired.Add(1)
+			return nil, ErrCacheExpired
+		}
+
+		c.stats.stale.Add(1)
+		return &item, nil
+	}
+
+	if item.Err != nil {
+		c.stats.errHits.Add(1)
+	} else {
+		c.stats.hits.Add(1)
 	}
 
 	if (slices.Contains(options, ResetTimer) && item.Err == nil) ||
 		(item.Err != nil && slices.Contains(options, ResetTimerOnErr)) {
 		item.Expires = time.Now().Add(item.Duration)
 		c.Items[key] = item
+
+		if c.evictor != nil {
+			c.evictor.touch(key)
+		}
 	}
 
 	return &item, item.Err
 }
 
 func (c *Cache[K, V]) setUnsafe(key K, data *V, err error, expires ...time.Duration) {
+	c.setTaggedUnsafe(key, data, err, nil, expires...)
+}
+
+func (c *Cache[K, V]) setTaggedUnsafe(key K, data *V, err error, tags []string, expires ...time.Duration) {
+	c.stats.sets.Add(1)
+
 	var holder V
 	if data != nil {
 		holder = *data
@@ -240,29 +452,143 @@ func (c *Cache[K, V]) setUnsafe(key K, data *V, err error, expires ...time.Durat
 		expiry = expires[0]
 	}
 
+	var current []string
+
 	if item, ok := c.Items[key]; ok {
 		if item.Duration == NoExpire || item.Duration > expiry {
 			expiry = item.Duration
 		}
+
+		// Entries still served as stale keep their tags so a background refresh
+		// stays reachable through InvalidateTag.
+		if now := time.Now(); item.Duration == NoExpire || !item.Expires.Before(now) || c.staleUnsafe(item, now) {
+			current = item.Tags
+		}
+	}
+
+	c.putUnsafe(key, cacheItem[V]{Expires: time.Now().Add(expiry), Err: err, Duration: expiry, Value: holder, Tags: mergeTags(current, tags)})
+}
+
+func (c *Cache[K, V]) putUnsafe(key K, item cacheItem[V]) {
+	old, exists := c.Items[key]
+	if exists {
+		if old.Duration != NoExpire && old.Expires.Before(time.Now()) {
+			c.recordUnsafe(key, old.Value, ReasonExpired)
+		} else {
+			c.recordUnsafe(key, old.Value, ReasonReplaced)
+		}
+
+		c.untagUnsafe(key, old.Tags)
+		c.bytes -= old.size
+	}
+
+	if c.sizer != nil {
+		item.size = c.sizer(key, item.Value)
+		c.bytes += item.size
+	}
+
+	c.Items[key] = item
+	c.tagUnsafe(key, item.Tags)
+
+	if c.evictor != nil {
+		c.evictor.insert(key)
+	}
+
+	c.enforceLimitUnsafe()
+}
+
+func (c *Cache[K, V]) deleteUnsafe(key K, reason evictReason) {
+	item, ok := c.Items[key]
+	if !ok {
+		return
+	}
+
+	c.recordUnsafe(key, item.Value, reason)
+	c.untagUnsafe(key, item.Tags)
+	c.bytes -= item.size
+	delete(c.Items, key)
+
+	if c.evictor != nil {
+		c.evictor.remove(key)
+	}
+}
+
+func (c *Cache[K, V]) victimUnsafe() (K, bool) {
+	if c.evictor != nil {
+		return c.evictor.victim()
 	}
 
-	c.Items[key] = cacheItem[V]{Expires: time.Now().Add(expiry), Err: err, Duration: expiry, Value: holder}
+	for key := range c.Items {
+		return key, true
+	}
+
+	var empty K
+	return empty, false
+}
+
+func (c *Cache[K, V]) overLimitUnsafe() bool {
+	if c.Limit != NoLimit && len(c.Items) > c.Limit {
+		return true
+	}
+
+	return c.maxBytes > 0 && c.bytes > c.maxBytes
+}
+
+func (c *Cache[K, V]) enforceLimitUnsafe() {
+	for c.overLimitUnsafe() {
+		key, ok := c.victimUnsafe()
+		if !ok {
+			break
+		}
+		c.deleteUnsafe(key, ReasonLimit)
+	}
 }
 
 func (c *Cache[K, V]) Clear() {
+	if store := c.clearLocal(); store != nil {
+		store.Clear()
+	}
+
+	c.publish(Invalidation{Op: OpClear})
+}
+
+func (c *Cache[K, V]) clearLocal() Store[K, V] {
 	c.mu.Lock()
+	for key, item := range c.Items {
+		c.recordUnsafe(key, item.Value, ReasonCleared)
+	}
 	c.Items = make(map[K]cacheItem[V])
-	c.mu.Unlock()
+	c.evictor = newEvictor[K](c.policy)
+	c.tags = nil
+	c.bytes = 0
+	store := c.store
+	c.unlock()
+
+	return store
 }
 
 func (c *Cache[K, V]) Remove(key K) bool {
+	ok, store := c.removeLocal(key)
+
+	if store != nil {
+		store.Delete(key)
+	}
+
+	c.publishKey(key)
+
+	return ok
+}
+
+func (c *Cache[K, V]) removeLocal(key K) (bool, Store[K, V]) {
 	c.mu.Lock()
 	_, ok := c.Items[key]
 	if ok {
-		delete(c.Items, key)
+		c.deleteUnsafe(key, ReasonRemoved)
 	}
-	c.mu.Unlock()
-	return ok
+	store := c.store
+	c.unlock()
+
+	return ok, store
 }
 
 func (c *Cache[K, V]) GetItems() [][]byte {
@@ -286,28 +612,24 @@ func (c *Cache[K, V]) Length() int {
 	return length
 }
 
+func (c *Cache[K, V]) Bytes() int {
+	c.mu.RLock()
+	bytes := c.bytes
+	c.mu.RUnlock()
+	return bytes
+}
+
 func (c *Cache[K, V]) Check() {
 	c.mu.Lock()
 	now := time.Now()
 
 	for key, item := range c.Items {
-		if item.Duration != NoExpire && now.After(item.Expires) {
-			delete(c.Items, key)
-		}
-	}
-
-	if c.Limit != NoLimit {
-		for len(c.Items) > c.Limit {
-			for key := range c.Items {
-				delete(c.Items, key)
-				break
-			}
+		if item.Duration != NoExpire && now.After(item.Expires) && !c.staleUnsafe(item, now) {
+			c.deleteUnsafe(key, ReasonExpired)
 		}
 	}
 
-	c.mu.Unlock()
-}
+	c.enforceLimitUnsafe()
 
-func init() {
-	go keeper._cleanup()
+	c.unlock()
 }
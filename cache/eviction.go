@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+const (
+	PolicyRandom evictionPolicy = iota
+	PolicyLRU
+	PolicyLFU
+	PolicyFIFO
+)
+
+const (
+	ReasonExpired evictReason = iota
+	ReasonLimit
+	ReasonRemoved
+	ReasonCleared
+	ReasonReplaced
+	ReasonInvalidated
+
+	numReasons
+)
+
+type evictionPolicy int
+type evictReason int
+
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason evictReason
+}
+
+type evictor[K comparable] interface {
+	insert(key K)
+	touch(key K)
+	remove(key K)
+	victim() (K, bool)
+}
+
+func newEvictor[K comparable](policy evictionPolicy) evictor[K] {
+	switch policy {
+	case PolicyLRU:
+		return newListEvictor[K](true)
+	case PolicyFIFO:
+		return newListEvictor[K](false)
+	case PolicyLFU:
+		return &lfuEvictor[K]{entries: map[K]*lfuEntry[K]{}}
+	}
+
+	return nil
+}
+
+type listEvictor[K comparable] struct {
+	order   *list.List
+	entries map[K]*list.Element
+	recency bool
+}
+
+func newListEvictor[K comparable](recency bool) *listEvictor[K] {
+	return &listEvictor[K]{order: list.New(), entries: map[K]*list.Element{}, recency: recency}
+}
+
+func (e *listEvictor[K]) insert(key K) {
+	if _, ok := e.entries[key]; ok {
+		e.touch(key)
+		return
+	}
+
+	e.entries[key] = e.order.PushBack(key)
+}
+
+func (e *listEvictor[K]) touch(key K) {
+	if elem, ok := e.entries[key]; ok && e.recency {
+		e.order.MoveToBack(elem)
+	}
+}
+
+func (e *listEvictor[K]) remove(key K) {
+	if elem, ok := e.entries[key]; ok {
+		e.order.Remove(elem)
+		delete(e.entries, key)
+	}
+}
+
+func (e *listEvictor[K]) victim() (K, bool) {
+	front := e.order.Front()
+	if front == nil {
+		var empty K
+		return empty, false
+	}
+
+	return front.Value.(K), true
+}
+
+type lfuEntry[K comparable] struct {
+	key   K
+	hits  uint64
+	tick  uint64
+	index int
+}
+
+type lfuEvictor[K comparable] struct {
+	heap    lfuHeap[K]
+	entries map[K]*lfuEntry[K]
+	tick    uint64
+}
+
+func (e *lfuEvictor[K]) insert(key K) {
+	if _, ok := e.entries[key]; ok {
+		e.touch(key)
+		return
+	}
+
+	e.tick++
+	entry := &lfuEntry[K]{key: key, hits: 1, tick: e.tick}
+	e.entries[key] = entry
+	heap.Push(&e.heap, entry)
+}
+
+func (e *lfuEvictor[K]) touch(key K) {
+	entry, ok := e.entries[key]
+	if !ok {
+		return
+	}
+
+	e.tick++
+	entry.hits++
+	entry.tick = e.tick
+	heap.Fix(&e.heap, entry.index)
+}
+
+func (e *lfuEvictor[K]) remove(key K) {
+	if entry, ok := e.entries[key]; ok {
+		heap.Remove(&e.heap, entry.index)
+		delete(e.entries, key)
+	}
+}
+
+func (e *lfuEvictor[K]) victim() (K, bool) {
+	if len(e.heap) == 0 {
+		var empty K
+		return empty, false
+	}
+
+	return e.heap[0].key, true
+}
+
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].hits != h[j].hits {
+		return h[i].hits < h[j].hits
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x any) {
+	entry := x.(*lfuEntry[K])
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+func (r evictReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonLimit:
+		return "limit"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonCleared:
+		return "cleared"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonInvalidated:
+		return "invalidated"
+	}
+
+	return "unknown"
+}
+
+func (c *Cache[K, V]) recordUnsafe(key K, value V, reason evictReason) {
+	c.stats.evictions[reason].Add(1)
+
+	if c.onEvict != nil {
+		c.pending = append(c.pending, evicted[K, V]{key: key, value: value, reason: reason})
+	}
+}
+
+// unlock releases the write lock and only then runs the OnEvict callback for
+// everything evicted while it was held.
+func (c *Cache[K, V]) unlock() {
+	pending, fn := c.pending, c.onEvict
+	c.pending = nil
+	c.mu.Unlock()
+
+	for _, e := range pending {
+		fn(e.key, e.value, e.reason)
+	}
+}
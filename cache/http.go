@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const defaultPageSize = 100
+
+var (
+	ttlLabels = []string{"expired", "<1m", "<5m", "<15m", "<1h", "<24h", ">=24h", "never"}
+	ttlLimits = []time.Duration{0, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 24 * time.Hour}
+)
+
+type ttlBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type keyInfo struct {
+	Key     json.RawMessage `json:"key"`
+	Expires *time.Time      `json:"expires,omitempty"`
+	TTL     string          `json:"ttl"`
+	Err     string          `json:"err,omitempty"`
+	Tags    []string        `json:"tags,omitempty"`
+}
+
+type cacheInspection struct {
+	Name   string      `json:"name"`
+	Size   int         `json:"size"`
+	Limit  int         `json:"limit"`
+	Bytes  int         `json:"bytes,omitempty"`
+	TTL    []ttlBucket `json:"ttl"`
+	Offset int         `json:"offset"`
+	Keys   []keyInfo   `json:"keys,omitempty"`
+	Stats  CacheStats  `json:"stats"`
+}
+
+type debugInter interface {
+	Stats() CacheStats
+	Clear()
+	label() string
+	inspect(offset, limit int) cacheInspection
+	removeRaw(raw string) bool
+}
+
+type DebugHandler struct {
+	keeper *CacheKeeper
+	auth   func(r *http.Request) bool
+}
+
+func NewDebugHandler(keeper *CacheKeeper, auth ...func(r *http.Request) bool) *DebugHandler {
+	handler := &DebugHandler{keeper: keeper}
+	if len(auth) > 0 {
+		handler.auth = auth[0]
+	}
+
+	return handler
+}
+
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("cache")
+
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			h.list(w)
+			return
+		}
+
+		cache, ok := h.find(name)
+		if !ok {
+			http.Error(w, "cache not found", http.StatusNotFound)
+			return
+		}
+
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 {
+			limit = defaultPageSize
+		}
+
+		inspection := cache.inspect(max(offset, 0), limit)
+		inspection.Name = name
+		writeJSON(w, inspection)
+	case http.MethodPost, http.MethodDelete:
+		if h.auth != nil && !h.auth(r) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		cache, ok := h.find(name)
+		if !ok {
+			http.Error(w, "cache not found", http.StatusNotFound)
+			return
+		}
+
+		switch query.Get("action") {
+		case "remove":
+			writeJSON(w, map[string]bool{"removed": cache.removeRaw(query.Get("key"))})
+		case "clear":
+			cache.Clear()
+			writeJSON(w, map[string]bool{"cleared": true})
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *DebugHandler) list(w http.ResponseWriter) {
+	caches := h.caches()
+
+	summaries := make([]cacheInspection, 0, len(caches))
+	for _, cache := range caches {
+		inspection := cache.inspect(0, 0)
+		inspection.Name = cache.label()
+		summaries = append(summaries, inspection)
+	}
+
+	writeJSON(w, summaries)
+}
+
+func (h *DebugHandler) find(name string) (debugInter, bool) {
+	for _, cache := range h.caches() {
+		if cache.label() == name {
+			return cache, true
+		}
+	}
+
+	return nil, false
+}
+
+// Only caches from this package can be inspected; other keeper members are
+// skipped.
+func (h *DebugHandler) caches() []debugInter {
+	h.keeper.mu.Lock()
+	caches := make([]debugInter, 0, len(h.keeper.Caches))
+	for _, cache := range h.keeper.Caches {
+		if cache, ok := cache.(debugInter); ok {
+			caches = append(caches, cache)
+		}
+	}
+	h.keeper.mu.Unlock()
+	return caches
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Unnamed caches are labelled by an ID fixed at construction, so a label keeps
+// pointing at the same cache while the keeper list changes.
+func (c *Cache[K, V]) label() string {
+	if c.name != "" {
+		return c.name
+	}
+
+	return fmt.Sprintf("#%d", c.id)
+}
+
+func (c *Cache[K, V]) inspect(offset, limit int) cacheInspection {
+	now := time.Now()
+
+	inspection := cacheInspection{Name: c.name, Offset: offset, Stats: c.Stats()}
+	counts := make([]int, len(ttlLabels))
+	var keys []keyInfo
+
+	c.mu.RLock()
+	inspection.Size = len(c.Items)
+	inspection.Limit = c.Limit
+	inspection.Bytes = c.bytes
+
+	for key, item := range c.Items {
+		info := keyInfo{TTL: "never", Tags: item.Tags}
+		if item.Err != nil {
+			info.Err = item.Err.Error()
+		}
+
+		if item.Duration == NoExpire {
+			counts[len(counts)-1]++
+		} else {
+			ttl := item.Expires.Sub(now)
+			expires := item.Expires
+			info.Expires = &expires
+			info.TTL = ttl.Round(time.Second).String()
+
+			index := slices.IndexFunc(ttlLimits, func(limit time.Duration) bool {
+				return ttl <= limit
+			})
+			if index < 0 {
+				index = len(ttlLimits)
+			} else if index == 0 {
+				info.TTL = "expired"
+			}
+			counts[index]++
+		}
+
+		if limit > 0 {
+			raw, err := json.Marshal(key)
+			if err != nil {
+				raw, _ = json.Marshal(fmt.Sprint(key))
+			}
+			info.Key = raw
+			keys = append(keys, info)
+		}
+	}
+	c.mu.RUnlock()
+
+	for i, label := range ttlLabels {
+		inspection.TTL = append(inspection.TTL, ttlBucket{Label: label, Count: counts[i]})
+	}
+
+	if limit > 0 && offset < len(keys) {
+		slices.SortFunc(keys, func(a, b keyInfo) int {
+			return cmp.Compare(string(a.Key), string(b.Key))
+		})
+		inspection.Keys = keys[offset:min(offset+limit, len(keys))]
+	}
+
+	return inspection
+}
+
+func (c *Cache[K, V]) removeRaw(raw string) bool {
+	var key K
+	if err := json.Unmarshal([]byte(raw), &key); err != nil {
+		if err := json.Unmarshal([]byte(strconv.Quote(raw)), &key); err != nil {
+			return false
+		}
+	}
+
+	return c.Remove(key)
+}
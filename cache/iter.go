@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"iter"
+	"time"
+)
+
+type CacheEntry[V any] struct {
+	Value   V
+	Err     error
+	Expires time.Time
+	TTL     time.Duration
+	Tags    []string
+}
+
+// All yields values only, so entries holding a stored error are left to Keys
+// and Entry.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := time.Now()
+
+		c.mu.RLock()
+		keys := make([]K, 0, len(c.Items))
+		values := make([]V, 0, len(c.Items))
+		for key, item := range c.Items {
+			if item.Err == nil && liveItem(item, now) {
+				keys = append(keys, key)
+				values = append(values, item.Value)
+			}
+		}
+		c.mu.RUnlock()
+
+		for i, key := range keys {
+			if !yield(key, values[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Cache[K, V]) Keys() []K {
+	now := time.Now()
+
+	c.mu.RLock()
+	keys := make([]K, 0, len(c.Items))
+	for key, item := range c.Items {
+		if liveItem(item, now) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.RUnlock()
+
+	return keys
+}
+
+func (c *Cache[K, V]) Entry(key K) (CacheEntry[V], bool) {
+	now := time.Now()
+
+	c.mu.RLock()
+	item, ok := c.Items[key]
+	c.mu.RUnlock()
+
+	if !ok || !liveItem(item, now) {
+		return CacheEntry[V]{}, false
+	}
+
+	entry := CacheEntry[V]{Value: item.Value, Err: item.Err, TTL: NoExpire, Tags: item.Tags}
+	if item.Duration != NoExpire {
+		entry.Expires = item.Expires
+		entry.TTL = item.Expires.Sub(now)
+	}
+
+	return entry, true
+}
+
+func liveItem[V any](item cacheItem[V], now time.Time) bool {
+	return item.Duration == NoExpire || !item.Expires.Before(now)
+}
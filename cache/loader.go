@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLoaderPanic = errors.New("cache: loader panicked")
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value *V
+	err   error
+}
+
+func (c *Cache[K, V]) GetOrLoad(key K, loader func() (V, error), expires time.Duration, options ...cacheOption) *V {
+	v, err := c.GetOrLoadErr(key, loader, expires, options...)
+	if err != nil {
+		return nil
+	}
+
+	return v
+}
+
+func (c *Cache[K, V]) GetOrLoadErr(key K, loader func() (V, error), expires time.Duration, options ...cacheOption) (*V, error) {
+	if v, ok, err := c.lookup(key, options...); ok {
+		return v, err
+	}
+
+	call := c.startLoad(key, loader, expires)
+	<-call.done
+
+	return call.value, call.err
+}
+
+func (c *Cache[K, V]) GetOrLoadCtx(ctx context.Context, key K, loader func(ctx context.Context) (V, error), expires time.Duration, options ...cacheOption) (*V, error) {
+	if v, ok, err := c.lookup(key, options...); ok {
+		return v, err
+	}
+
+	loadCtx := context.WithoutCancel(ctx)
+
+	call := c.startLoad(key, func() (V, error) {
+		return loader(loadCtx)
+	}, expires)
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Cache[K, V]) lookup(key K, options ...cacheOption) (*V, bool, error) {
+	v, err := c.GetErr(key, options...)
+
+	switch {
+	case errors.Is(err, ErrCacheNotFound), errors.Is(err, ErrCacheExpired):
+		return nil, false, err
+	case err != nil:
+		return nil, true, err
+	}
+
+	return v, true, nil
+}
+
+func (c *Cache[K, V]) peek(key K) (*V, bool, error) {
+	c.mu.RLock()
+	item, ok := c.Items[key]
+	c.mu.RUnlock()
+
+	if !ok || (item.Duration != NoExpire && item.Expires.Before(time.Now())) {
+		return nil, false, nil
+	} else if item.Err != nil {
+		return nil, true, item.Err
+	}
+
+	return &item.Value, true, nil
+}
+
+func (c *Cache[K, V]) startLoad(key K, loader func() (V, error), expires time.Duration) *loadCall[V] {
+	call, leader := c.registerLoad(key)
+	if leader {
+		go c.runLoad(key, call, loader, expires)
+	}
+
+	return call
+}
+
+func (c *Cache[K, V]) registerLoad(key K) (*loadCall[V], bool) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	if call, ok := c.loads[key]; ok {
+		return call, false
+	}
+
+	if c.loads == nil {
+		c.loads = map[K]*loadCall[V]{}
+	}
+
+	call := &loadCall[V]{done: make(chan struct{})}
+	c.loads[key] = call
+
+	return call, true
+}
+
+func (c *Cache[K, V]) finishLoad(key K, call *loadCall[V]) {
+	c.loadMu.Lock()
+	delete(c.loads, key)
+	c.loadMu.Unlock()
+	close(call.done)
+}
+
+func (c *Cache[K, V]) runLoad(key K, call *loadCall[V], loader func() (V, error), expires time.Duration) {
+	defer c.finishLoad(key, call)
+
+	// A load for the same key may have finished between the caller's miss and
+	// this call being registered.
+	if v, ok, err := c.peek(key); ok {
+		call.value, call.err = v, err
+		return
+	}
+
+	value, err := safeLoad(loader)
+	if err != nil {
+		c.SetErr(key, nil, err, expires)
+		call.err = err
+		return
+	}
+
+	c.SetErr(key, &value, nil, expires)
+	call.value = &value
+}
+
+func safeLoad[V any](loader func() (V, error)) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrLoaderPanic, r)
+		}
+	}()
+
+	return loader()
+}
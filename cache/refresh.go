@@ -0,0 +1,126 @@
+package cache
+
+import "time"
+
+type RefreshConfig struct {
+	StaleFor     time.Duration
+	RefreshAhead float64
+	MaxInFlight  int
+}
+
+type refresher[K comparable, V any] struct {
+	fn     func(key K) (V, error)
+	config RefreshConfig
+	sem    chan struct{}
+}
+
+func (c *Cache[K, V]) SetRefresher(fn func(key K) (V, error), config RefreshConfig) *Cache[K, V] {
+	var r *refresher[K, V]
+
+	if fn != nil {
+		r = &refresher[K, V]{fn: fn, config: config}
+		if config.MaxInFlight > 0 {
+			r.sem = make(chan struct{}, config.MaxInFlight)
+		}
+	}
+
+	c.mu.Lock()
+	c.refresher = r
+	c.mu.Unlock()
+
+	return c
+}
+
+func (c *Cache[K, V]) staleUnsafe(item cacheItem[V], now time.Time) bool {
+	if c.refresher == nil || item.Err != nil {
+		return false
+	}
+
+	return now.Before(item.Expires.Add(c.refresher.config.StaleFor))
+}
+
+func (c *Cache[K, V]) maybeRefresh(r *refresher[K, V], key K, item *cacheItem[V]) {
+	if item.Duration == NoExpire || item.Err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	if now.Before(item.Expires) {
+		ahead := r.config.RefreshAhead
+		if ahead <= 0 || ahead >= 1 {
+			return
+		}
+
+		refreshAt := item.Expires.Add(-time.Duration(float64(item.Duration) * (1 - ahead)))
+		if now.Before(refreshAt) {
+			return
+		}
+	}
+
+	c.refresh(r, key, item.Duration)
+}
+
+func (c *Cache[K, V]) refresh(r *refresher[K, V], key K, expires time.Duration) {
+	if r.sem != nil {
+		select {
+		case r.sem <- struct{}{}:
+		default:
+			return
+		}
+	}
+
+	if !c.registerRefresh(key) {
+		if r.sem != nil {
+			<-r.sem
+		}
+		return
+	}
+
+	c.stats.refreshes.Add(1)
+
+	go func() {
+		defer func() {
+			if r.sem != nil {
+				<-r.sem
+			}
+			c.finishRefresh(key)
+		}()
+
+		// A failed refresh keeps serving the stale value until StaleFor runs out.
+		value, err := safeLoad(func() (V, error) {
+			return r.fn(key)
+		})
+		if err != nil {
+			return
+		}
+
+		c.SetErr(key, &value, nil, expires)
+	}()
+}
+
+// Refreshes are tracked apart from GetOrLoad calls so a caller that misses
+// never waits on, or inherits the error of, a background refresh.
+func (c *Cache[K, V]) registerRefresh(key K) bool {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	if _, ok := c.refreshing[key]; ok {
+		return false
+	} else if _, ok := c.loads[key]; ok {
+		return false
+	}
+
+	if c.refreshing == nil {
+		c.refreshing = map[K]struct{}{}
+	}
+	c.refreshing[key] = struct{}{}
+
+	return true
+}
+
+func (c *Cache[K, V]) finishRefresh(key K) {
+	c.loadMu.Lock()
+	delete(c.refreshing, key)
+	c.loadMu.Unlock()
+}
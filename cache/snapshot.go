@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	snapshotMagic   = "UCACHE"
+	snapshotVersion = 2
+)
+
+var (
+	ErrSnapshotFormat  = errors.New("cache: not a cache snapshot")
+	ErrSnapshotVersion = errors.New("cache: unsupported snapshot version")
+	ErrSnapshotCorrupt = errors.New("cache: corrupted snapshot")
+)
+
+type snapshotHeader struct {
+	Magic    [6]byte
+	Version  uint16
+	Length   uint32
+	Checksum uint32
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	Key      K             `json:"key"`
+	Value    V             `json:"value"`
+	Expires  time.Time     `json:"expires"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"err,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+}
+
+func (c *Cache[K, V]) Save(w io.Writer) error {
+	now := time.Now()
+
+	c.mu.RLock()
+	entries := make([]snapshotEntry[K, V], 0, len(c.Items))
+	for key, item := range c.Items {
+		if item.Duration != NoExpire && item.Expires.Before(now) {
+			continue
+		}
+
+		entry := snapshotEntry[K, V]{Key: key, Value: item.Value, Expires: item.Expires, Duration: item.Duration, Tags: item.Tags}
+		if item.Err != nil {
+			entry.Err = item.Err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	c.mu.RUnlock()
+
+	payload, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	header := snapshotHeader{
+		Version:  snapshotVersion,
+		Length:   uint32(len(payload)),
+		Checksum: crc32.ChecksumIEEE(payload),
+	}
+	copy(header.Magic[:], snapshotMagic)
+
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+	return err
+}
+
+func (c *Cache[K, V]) Load(r io.Reader) error {
+	var header snapshotHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrSnapshotFormat
+		}
+		return err
+	}
+
+	if string(header.Magic[:]) != snapshotMagic {
+		return ErrSnapshotFormat
+	} else if header.Version != snapshotVersion {
+		return fmt.Errorf("%w: %d (want %d)", ErrSnapshotVersion, header.Version, snapshotVersion)
+	}
+
+	// The buffer grows with the bytes actually read, so a damaged length field
+	// cannot force a large allocation before the checksum is verified.
+	var buf bytes.Buffer
+	if n, err := buf.ReadFrom(io.LimitReader(r, int64(header.Length))); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	} else if n != int64(header.Length) {
+		return fmt.Errorf("%w: truncated payload", ErrSnapshotCorrupt)
+	}
+
+	payload := buf.Bytes()
+	if crc32.ChecksumIEEE(payload) != header.Checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	var entries []snapshotEntry[K, V]
+	if err := json.Unmarshal(payload, &entries); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	for _, entry := range entries {
+		if entry.Duration != NoExpire && entry.Expires.Before(now) {
+			continue
+		}
+
+		item := cacheItem[V]{Expires: entry.Expires, Duration: entry.Duration, Value: entry.Value, Tags: entry.Tags}
+		if entry.Err != "" {
+			item.Err = errors.New(entry.Err)
+		}
+
+		c.putUnsafe(entry.Key, item)
+	}
+	c.unlock()
+
+	return nil
+}
+
+func (c *Cache[K, V]) SaveFile(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+
+	if err := c.Save(writer); err != nil {
+		file.Close()
+		return err
+	} else if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	} else if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (c *Cache[K, V]) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.Load(bytes.NewReader(data))
+}
@@ -0,0 +1,75 @@
+package cache
+
+import "sync/atomic"
+
+type cacheStats struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	expired   atomic.Uint64
+	errHits   atomic.Uint64
+	stale     atomic.Uint64
+	refreshes atomic.Uint64
+	storeHits atomic.Uint64
+	storeErrs atomic.Uint64
+	sets      atomic.Uint64
+	busErrors atomic.Uint64
+	evictions [numReasons]atomic.Uint64
+}
+
+type CacheStats struct {
+	Name        string            `json:"name"`
+	Hits        uint64            `json:"hits"`
+	Misses      uint64            `json:"misses"`
+	Expired     uint64            `json:"expired"`
+	ErrHits     uint64            `json:"err_hits"`
+	Stale       uint64            `json:"stale"`
+	Refreshes   uint64            `json:"refreshes"`
+	StoreHits   uint64            `json:"store_hits"`
+	StoreErrors uint64            `json:"store_errors"`
+	Sets        uint64            `json:"sets"`
+	BusErrors   uint64            `json:"bus_errors"`
+	Evictions   map[string]uint64 `json:"evictions"`
+	Size        int               `json:"size"`
+	Limit       int               `json:"limit"`
+	Bytes       int               `json:"bytes"`
+	MaxBytes    int               `json:"max_bytes"`
+}
+
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.ErrHits + s.Stale + s.Misses + s.Expired
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits+s.ErrHits+s.Stale) / float64(total)
+}
+
+func (c *Cache[K, V]) Stats() CacheStats {
+	stats := CacheStats{
+		Name:        c.name,
+		Hits:        c.stats.hits.Load(),
+		Misses:      c.stats.misses.Load(),
+		Expired:     c.stats.expired.Load(),
+		ErrHits:     c.stats.errHits.Load(),
+		Stale:       c.stats.stale.Load(),
+		Refreshes:   c.stats.refreshes.Load(),
+		StoreHits:   c.stats.storeHits.Load(),
+		StoreErrors: c.stats.storeErrs.Load(),
+		Sets:        c.stats.sets.Load(),
+		BusErrors:   c.stats.busErrors.Load(),
+		Evictions:   make(map[string]uint64, numReasons),
+	}
+
+	for reason := range numReasons {
+		stats.Evictions[reason.String()] = c.stats.evictions[reason].Load()
+	}
+
+	c.mu.RLock()
+	stats.Size = len(c.Items)
+	stats.Limit = c.Limit
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	c.mu.RUnlock()
+
+	return stats
+}
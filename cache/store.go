@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Store[K comparable, V any] interface {
+	Get(key K) (V, time.Duration, []string, error)
+	Set(key K, value V, ttl time.Duration, tags ...string) error
+	Delete(key K) error
+	DeleteTag(tag string) ([]K, error)
+	Clear() error
+}
+
+func (c *Cache[K, V]) SetStore(store Store[K, V]) *Cache[K, V] {
+	c.mu.Lock()
+	c.store = store
+	c.mu.Unlock()
+	return c
+}
+
+func (c *Cache[K, V]) promote(store Store[K, V], key K) (*V, bool) {
+	value, ttl, tags, err := store.Get(key)
+	if err != nil {
+		return nil, false
+	}
+
+	c.stats.storeHits.Add(1)
+
+	c.mu.Lock()
+	c.putUnsafe(key, cacheItem[V]{Expires: time.Now().Add(ttl), Duration: ttl, Value: value, Tags: tags})
+	c.unlock()
+
+	return &value, true
+}
+
+func (c *Cache[K, V]) writeThrough(store Store[K, V], key K, value V, tags []string, expires ...time.Duration) error {
+	if store == nil {
+		return nil
+	}
+
+	ttl := NoExpire
+	if len(expires) > 0 {
+		ttl = expires[0]
+	}
+
+	err := store.Set(key, value, ttl, tags...)
+	if err != nil {
+		c.stats.storeErrs.Add(1)
+	}
+
+	return err
+}
+
+func (c *Cache[K, V]) tagsUnsafe(key K) []string {
+	return c.Items[key].Tags
+}
+
+type memoryEntry[V any] struct {
+	value   V
+	expires time.Time
+	tags    []string
+}
+
+type MemoryStore[K comparable, V any] struct {
+	items map[K]memoryEntry[V]
+	mu    sync.Mutex
+}
+
+func NewMemoryStore[K comparable, V any]() *MemoryStore[K, V] {
+	return &MemoryStore[K, V]{items: map[K]memoryEntry[V]{}}
+}
+
+func (s *MemoryStore[K, V]) Get(key K) (V, time.Duration, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.items[key]
+	if !ok {
+		var empty V
+		return empty, 0, nil, ErrCacheNotFound
+	}
+
+	ttl, ok := remaining(entry.expires)
+	if !ok {
+		delete(s.items, key)
+		var empty V
+		return empty, 0, nil, ErrCacheExpired
+	}
+
+	return entry.value, ttl, slices.Clone(entry.tags), nil
+}
+
+func (s *MemoryStore[K, V]) Set(key K, value V, ttl time.Duration, tags ...string) error {
+	s.mu.Lock()
+	s.items[key] = memoryEntry[V]{value: value, expires: expiresAt(ttl), tags: slices.Clone(tags)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore[K, V]) Delete(key K) error {
+	s.mu.Lock()
+	delete(s.items, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore[K, V]) DeleteTag(tag string) ([]K, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []K
+	for key, entry := range s.items {
+		if slices.Contains(entry.tags, tag) {
+			delete(s.items, key)
+			removed = append(removed, key)
+		}
+	}
+
+	return removed, nil
+}
+
+func (s *MemoryStore[K, V]) Clear() error {
+	s.mu.Lock()
+	clear(s.items)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore[K, V]) Len() int {
+	s.mu.Lock()
+	length := len(s.items)
+	s.mu.Unlock()
+	return length
+}
+
+type fileEntry[K comparable, V any] struct {
+	Key     K         `json:"key"`
+	Value   V         `json:"value"`
+	Expires time.Time `json:"expires"`
+	Tags    []string  `json:"tags,omitempty"`
+}
+
+type FileStore[K comparable, V any] struct {
+	dir string
+}
+
+func NewFileStore[K comparable, V any](dir string) (*FileStore[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileStore[K, V]{dir: dir}, nil
+}
+
+func (s *FileStore[K, V]) Get(key K) (V, time.Duration, []string, error) {
+	var empty V
+
+	path, err := s.path(key)
+	if err != nil {
+		return empty, 0, nil, err
+	}
+
+	entry, err := s.read(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, 0, nil, ErrCacheNotFound
+	} else if err != nil {
+		return empty, 0, nil, err
+	}
+
+	ttl, ok := remaining(entry.Expires)
+	if !ok {
+		os.Remove(path)
+		return empty, 0, nil, ErrCacheExpired
+	}
+
+	return entry.Value, ttl, entry.Tags, nil
+}
+
+func (s *FileStore[K, V]) Set(key K, value V, ttl time.Duration, tags ...string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fileEntry[K, V]{Key: key, Value: value, Expires: expiresAt(ttl), Tags: tags})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(s.dir, ".entry.*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	} else if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (s *FileStore[K, V]) Delete(key K) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTag has no index to consult, so it reads every entry in the directory.
+func (s *FileStore[K, V]) DeleteTag(tag string) ([]K, error) {
+	paths, err := s.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []K
+	for _, path := range paths {
+		entry, err := s.read(path)
+		if err != nil || !slices.Contains(entry.Tags, tag) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, entry.Key)
+	}
+
+	return removed, nil
+}
+
+func (s *FileStore[K, V]) Clear() error {
+	paths, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore[K, V]) entries() ([]string, error) {
+	return filepath.Glob(filepath.Join(s.dir, "*.json"))
+}
+
+func (s *FileStore[K, V]) read(path string) (fileEntry[K, V], error) {
+	var entry fileEntry[K, V]
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+func (s *FileStore[K, V]) path(key K) (string, error) {
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl == NoExpire {
+		return time.Time{}
+	}
+
+	return time.Now().Add(ttl)
+}
+
+func remaining(expires time.Time) (time.Duration, bool) {
+	if expires.IsZero() {
+		return NoExpire, true
+	}
+
+	ttl := time.Until(expires)
+	return ttl, ttl > 0
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"slices"
+	"time"
+)
+
+func (c *Cache[K, V]) SetTagged(key K, data V, expires time.Duration, tags ...string) error {
+	c.mu.Lock()
+	c.setTaggedUnsafe(key, &data, nil, tags, expires)
+	store, merged := c.store, mergeTags(c.tagsUnsafe(key), tags)
+	c.unlock()
+
+	return c.writeThrough(store, key, data, merged, expires)
+}
+
+func (c *Cache[K, V]) InvalidateTag(tag string) int {
+	removed, store := c.invalidateTagLocal(tag)
+
+	// L2 keeps the tags of entries L1 has already evicted, so it is asked
+	// directly rather than fed the keys removed here.
+	if store != nil {
+		seen := make(map[K]struct{}, len(removed))
+		for _, key := range removed {
+			seen[key] = struct{}{}
+		}
+
+		stored, _ := store.DeleteTag(tag)
+		for _, key := range stored {
+			if _, ok := seen[key]; !ok {
+				removed = append(removed, key)
+			}
+		}
+	}
+
+	c.publish(Invalidation{Op: OpInvalidateTag, Tag: tag})
+
+	return len(removed)
+}
+
+func (c *Cache[K, V]) invalidateTagLocal(tag string) ([]K, Store[K, V]) {
+	c.mu.Lock()
+	var removed []K
+	for key := range c.tags[tag] {
+		removed = append(removed, key)
+	}
+
+	for _, key := range removed {
+		c.deleteUnsafe(key, ReasonInvalidated)
+	}
+	store := c.store
+	c.unlock()
+
+	return removed, store
+}
+
+func (c *Cache[K, V]) TagKeys(tag string) []K {
+	c.mu.RLock()
+	keys := make([]K, 0, len(c.tags[tag]))
+	for key := range c.tags[tag] {
+		keys = append(keys, key)
+	}
+	c.mu.RUnlock()
+
+	return keys
+}
+
+func (c *Cache[K, V]) tagUnsafe(key K, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	if c.tags == nil {
+		c.tags = map[string]map[K]struct{}{}
+	}
+
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = map[K]struct{}{}
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (c *Cache[K, V]) untagUnsafe(key K, tags []string) {
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			continue
+		}
+
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+func mergeTags(current, added []string) []string {
+	if len(added) == 0 {
+		return current
+	}
+
+	merged := slices.Clone(current)
+	for _, tag := range added {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,34 @@
+package utils
+
+const (
+	EventAdded evictingEventType = iota
+	EventRejected
+	EventEvicted
+	EventRemoved
+	EventUpdated
+)
+
+type evictingEventType int
+
+type EvictingEvent[K comparable, V any] struct {
+	Type  evictingEventType
+	Key   K
+	Value V
+}
+
+func (t evictingEventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventRejected:
+		return "rejected"
+	case EventEvicted:
+		return "evicted"
+	case EventRemoved:
+		return "removed"
+	case EventUpdated:
+		return "updated"
+	}
+
+	return "unknown"
+}
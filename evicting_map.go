@@ -1,79 +1,213 @@
 package utils
 
 import (
+	"container/list"
+	"encoding/json"
 	"slices"
 	"sync"
 )
 
+const (
+	evictingMapMagic   = "UEVMAP"
+	evictingMapVersion = 1
+)
+
+type evictingEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+type evictingMapItem[K comparable, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+type evictingMapState[K comparable, V any] struct {
+	Max   int                     `json:"max"`
+	Items []evictingMapItem[K, V] `json:"items"`
+}
+
 type EvictingMap[K comparable, V any] struct {
-	values      map[K]V
-	order       []K
+	values      map[K]*list.Element
+	order       *list.List
 	max         int
-	mu          sync.RWMutex `json:"-"`
+	lru         bool
+	refresh     bool
+	mu          sync.RWMutex
+	emitMu      sync.Mutex
+	pending     []EvictingEvent[K, V]
 	Broadcaster *Broadcaster[K]
+	Events      *Broadcaster[EvictingEvent[K, V]]
 	allowFunc   func(K K, V V) bool
 }
 
 func (rd *EvictingMap[K, V]) Add(id K, value V) {
-	if rd.Broadcaster != nil {
-		rd.Broadcaster.Broadcast(id)
-	}
+	rd.broadcast(id)
 
 	rd.mu.Lock()
 	if _, exists := rd.values[id]; !exists {
-
-		if rd.allowFunc != nil && !rd.allowFunc(id, value) {
-			rd.mu.Unlock()
-			return
-		}
-
-		rd.values[id] = value
-		rd.order = append(rd.order, id)
+		rd.store(id, value, false)
 	}
 
 	rd.evictItems()
 
-	rd.mu.Unlock()
+	rd.unlock()
 }
 
 func (rd *EvictingMap[K, V]) Replace(id K, value V) {
-	rd.Remove(id)
-	rd.Add(id, value)
+	rd.broadcast(id)
+
+	rd.mu.Lock()
+	rd.store(id, value, true)
+	rd.unlock()
+}
+
+func (rd *EvictingMap[K, V]) Set(id K, value V) bool {
+	rd.mu.Lock()
+	stored := rd.store(id, value, rd.refresh || rd.lru)
+	rd.unlock()
+
+	if stored {
+		rd.broadcast(id)
+	}
+	return stored
+}
+
+func (rd *EvictingMap[K, V]) Update(id K, fn func(value V, exists bool) (V, bool)) (V, bool) {
+	rd.mu.Lock()
+	value, exists, stored := rd.update(id, fn)
+	rd.unlock()
+
+	if stored {
+		rd.broadcast(id)
+	}
+	return value, exists
+}
+
+func (rd *EvictingMap[K, V]) update(id K, fn func(value V, exists bool) (V, bool)) (V, bool, bool) {
+	var current V
+	elem, exists := rd.values[id]
+	if exists {
+		current = elem.Value.(*evictingEntry[K, V]).value
+	}
+
+	value, ok := fn(current, exists)
+	if !ok || !rd.store(id, value, rd.refresh || rd.lru) {
+		return current, exists, false
+	}
+
+	return value, true, true
+}
+
+// GetOrAdd reports whether the value was already present and whether the key
+// is in the map afterwards; ok is false only when allowFunc rejected it.
+func (rd *EvictingMap[K, V]) GetOrAdd(id K, value V) (actual V, loaded bool, ok bool) {
+	rd.mu.Lock()
+	actual, loaded, ok = rd.getOrAdd(id, value)
+	rd.unlock()
+
+	if ok && !loaded {
+		rd.broadcast(id)
+	}
+	return actual, loaded, ok
+}
+
+func (rd *EvictingMap[K, V]) getOrAdd(id K, value V) (V, bool, bool) {
+	if elem, exists := rd.values[id]; exists {
+		if rd.lru {
+			rd.order.MoveToBack(elem)
+		}
+		return elem.Value.(*evictingEntry[K, V]).value, true, true
+	}
+
+	if !rd.store(id, value, false) {
+		var empty V
+		return empty, false, false
+	}
+
+	return value, false, true
+}
+
+func (rd *EvictingMap[K, V]) store(id K, value V, moveToBack bool) bool {
+	if rd.allowFunc != nil && !rd.allowFunc(id, value) {
+		rd.emit(EventRejected, id, value)
+		return false
+	}
+
+	if elem, exists := rd.values[id]; exists {
+		elem.Value.(*evictingEntry[K, V]).value = value
+		if moveToBack {
+			rd.order.MoveToBack(elem)
+		}
+		rd.emit(EventUpdated, id, value)
+		return true
+	}
+
+	rd.values[id] = rd.order.PushBack(&evictingEntry[K, V]{key: id, value: value})
+	rd.emit(EventAdded, id, value)
+	rd.evictItems()
+	return true
 }
 
 func (rd *EvictingMap[K, V]) Remove(item K) {
 	rd.mu.Lock()
 
-	delete(rd.values, item)
-	rd.order = slices.DeleteFunc(rd.order, func(k K) bool {
-		return k == item
-	})
+	if elem, ok := rd.values[item]; ok {
+		rd.order.Remove(elem)
+		delete(rd.values, item)
+		rd.emit(EventRemoved, item, elem.Value.(*evictingEntry[K, V]).value)
+	}
 
-	rd.mu.Unlock()
+	rd.unlock()
 }
 
 func (rd *EvictingMap[K, V]) Get(item K) (V, bool) {
 	rd.mu.RLock()
-	value, exists := rd.values[item]
+	if !rd.lru {
+		defer rd.mu.RUnlock()
+		return rd.get(item)
+	}
 	rd.mu.RUnlock()
-	return value, exists
+
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	return rd.get(item)
+}
+
+func (rd *EvictingMap[K, V]) get(item K) (V, bool) {
+	elem, exists := rd.values[item]
+	if !exists {
+		var empty V
+		return empty, false
+	}
+
+	if rd.lru {
+		rd.order.MoveToBack(elem)
+	}
+
+	return elem.Value.(*evictingEntry[K, V]).value, true
 }
 
 func (rd *EvictingMap[K, V]) Clear() {
 	rd.mu.Lock()
-	rd.values = make(map[K]V)
-	rd.order = rd.order[:0]
-	rd.mu.Unlock()
+	if rd.Events != nil {
+		for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+			entry := elem.Value.(*evictingEntry[K, V])
+			rd.emit(EventRemoved, entry.key, entry.value)
+		}
+	}
+
+	rd.values = make(map[K]*list.Element)
+	rd.order.Init()
+	rd.unlock()
 }
 
 func (rd *EvictingMap[K, V]) Items() []V {
 	rd.mu.RLock()
 
-	result := make([]V, 0, len(rd.order))
-	for _, key := range rd.order {
-		if val, ok := rd.values[key]; ok {
-			result = append(result, val)
-		}
+	result := make([]V, 0, rd.order.Len())
+	for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+		result = append(result, elem.Value.(*evictingEntry[K, V]).value)
 	}
 
 	rd.mu.RUnlock()
@@ -94,15 +228,13 @@ func (rd *EvictingMap[K, V]) Last() (V, bool) {
 	rd.mu.RLock()
 	defer rd.mu.RUnlock()
 
-	if len(rd.order) == 0 {
+	last := rd.order.Back()
+	if last == nil {
 		var empty V
 		return empty, false
 	}
 
-	last := rd.order[len(rd.order)-1]
-	value, exists := rd.values[last]
-
-	return value, exists
+	return last.Value.(*evictingEntry[K, V]).value, true
 }
 
 func (rd *EvictingMap[K, V]) AllowFunc(fn func(K K, V V) bool) *EvictingMap[K, V] {
@@ -120,23 +252,122 @@ func (rd *EvictingMap[K, V]) Len() int {
 func (rd *EvictingMap[K, V]) SetMax(max int) *EvictingMap[K, V] {
 	rd.mu.Lock()
 	if rd.max == max {
-		rd.mu.Unlock()
+		rd.unlock()
 		return rd
 	}
 
 	rd.max = max
 	rd.evictItems()
-	rd.mu.Unlock()
+	rd.unlock()
 
 	return rd
 }
 
 func (rd *EvictingMap[K, V]) evictItems() {
-	for len(rd.values) > rd.max && len(rd.order) > 0 {
-		evictKey := rd.order[0]
-		rd.order = rd.order[1:]
-		delete(rd.values, evictKey)
+	for len(rd.values) > rd.max {
+		front := rd.order.Front()
+		if front == nil {
+			return
+		}
+
+		entry := front.Value.(*evictingEntry[K, V])
+		rd.order.Remove(front)
+		delete(rd.values, entry.key)
+		rd.emit(EventEvicted, entry.key, entry.value)
+	}
+}
+
+func (rd *EvictingMap[K, V]) broadcast(id K) {
+	if rd.Broadcaster != nil {
+		rd.Broadcaster.Broadcast(id)
+	}
+}
+
+func (rd *EvictingMap[K, V]) emit(event evictingEventType, key K, value V) {
+	if rd.Events != nil {
+		rd.pending = append(rd.pending, EvictingEvent[K, V]{Type: event, Key: key, Value: value})
+	}
+}
+
+// unlock releases the write lock and only then broadcasts the events queued
+// while it was held; emitMu keeps them in the order the changes were made.
+func (rd *EvictingMap[K, V]) unlock() {
+	pending := rd.pending
+	rd.pending = nil
+	if len(pending) == 0 {
+		rd.mu.Unlock()
+		return
+	}
+
+	rd.emitMu.Lock()
+	rd.mu.Unlock()
+	for _, event := range pending {
+		rd.Events.Broadcast(event)
+	}
+	rd.emitMu.Unlock()
+}
+
+func (rd *EvictingMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rd.state())
+}
+
+func (rd *EvictingMap[K, V]) UnmarshalJSON(data []byte) error {
+	var state evictingMapState[K, V]
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	rd.restore(state)
+	return nil
+}
+
+func (rd *EvictingMap[K, V]) MarshalBinary() ([]byte, error) {
+	return marshalEvicting(evictingMapMagic, evictingMapVersion, rd.state())
+}
+
+func (rd *EvictingMap[K, V]) UnmarshalBinary(data []byte) error {
+	var state evictingMapState[K, V]
+	if err := unmarshalEvicting(evictingMapMagic, evictingMapVersion, data, &state); err != nil {
+		return err
+	}
+
+	rd.restore(state)
+	return nil
+}
+
+func (rd *EvictingMap[K, V]) state() evictingMapState[K, V] {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	state := evictingMapState[K, V]{Max: rd.max, Items: make([]evictingMapItem[K, V], 0, len(rd.values))}
+	if rd.order == nil {
+		return state
+	}
+
+	for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*evictingEntry[K, V])
+		state.Items = append(state.Items, evictingMapItem[K, V]{Key: entry.key, Value: entry.value})
 	}
+
+	return state
+}
+
+func (rd *EvictingMap[K, V]) restore(state evictingMapState[K, V]) {
+	rd.mu.Lock()
+	defer rd.unlock()
+
+	rd.values = make(map[K]*list.Element, len(state.Items))
+	rd.order = list.New()
+	rd.max = state.Max
+
+	for _, item := range state.Items {
+		if elem, exists := rd.values[item.Key]; exists {
+			rd.order.Remove(elem)
+		}
+		rd.values[item.Key] = rd.order.PushBack(&evictingEntry[K, V]{key: item.Key, value: item.Value})
+	}
+
+	rd.evictItems()
 }
 
 func (rd *EvictingMap[K, V]) WithBroadcaster() *EvictingMap[K, V] {
@@ -144,6 +375,25 @@ func (rd *EvictingMap[K, V]) WithBroadcaster() *EvictingMap[K, V] {
 	return rd
 }
 
+func (rd *EvictingMap[K, V]) WithEvents() *EvictingMap[K, V] {
+	rd.Events = NewBroadcaster[EvictingEvent[K, V]]()
+	return rd
+}
+
+func (rd *EvictingMap[K, V]) RefreshOnSet(refresh bool) *EvictingMap[K, V] {
+	rd.mu.Lock()
+	rd.refresh = refresh
+	rd.mu.Unlock()
+	return rd
+}
+
+func (rd *EvictingMap[K, V]) WithLRU() *EvictingMap[K, V] {
+	rd.mu.Lock()
+	rd.lru = true
+	rd.mu.Unlock()
+	return rd
+}
+
 func NewEvictingMap[K comparable, V any](max int) *EvictingMap[K, V] {
-	return &EvictingMap[K, V]{values: map[K]V{}, max: max}
+	return &EvictingMap[K, V]{values: map[K]*list.Element{}, order: list.New(), max: max}
 }
@@ -1,16 +1,53 @@
 package utils
 
 import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"slices"
 	"sync"
+	"time"
 )
 
+const (
+	evictingSetMagic   = "UEVSET"
+	evictingSetVersion = 1
+)
+
+var (
+	ErrEvictingFormat  = errors.New("evicting: not a snapshot")
+	ErrEvictingVersion = errors.New("evicting: unsupported snapshot version")
+)
+
+type evictingSetEntry[T comparable] struct {
+	value T
+	added time.Time
+}
+
+type evictingSetItem[T comparable] struct {
+	Value T         `json:"value"`
+	Added time.Time `json:"added"`
+}
+
+type evictingSetState[T comparable] struct {
+	Max   int                  `json:"max"`
+	TTL   time.Duration        `json:"ttl,omitempty"`
+	Items []evictingSetItem[T] `json:"items"`
+}
+
 type EvictingSet[T comparable] struct {
-	values      map[T]struct{}
-	order       []T
+	values      map[T]*list.Element
+	order       *list.List
 	max         int
-	mu          sync.RWMutex `json:"-"`
+	ttl         time.Duration
+	mu          sync.RWMutex
+	emitMu      sync.Mutex
+	pending     []EvictingEvent[T, struct{}]
 	Broadcaster *Broadcaster[T]
+	Events      *Broadcaster[EvictingEvent[T, struct{}]]
 	allowFunc   func(T T) bool
 }
 
@@ -20,59 +57,87 @@ func (rd *EvictingSet[T]) Add(id T) {
 	}
 
 	rd.mu.Lock()
+	rd.add(id, time.Now())
+	rd.unlock()
+}
 
-	if _, exists := rd.values[id]; !exists {
-		if rd.allowFunc != nil && !rd.allowFunc(id) {
-			rd.mu.Unlock()
-			return
-		}
+func (rd *EvictingSet[T]) AddIfAbsent(id T) bool {
+	rd.mu.Lock()
+	added := rd.add(id, time.Now())
+	rd.unlock()
 
-		rd.values[id] = struct{}{}
-		rd.order = append(rd.order, id)
+	if added && rd.Broadcaster != nil {
+		rd.Broadcaster.Broadcast(id)
 	}
 
+	return added
+}
+
+func (rd *EvictingSet[T]) add(id T, now time.Time) bool {
+	rd.expireItems(now)
+
+	if _, exists := rd.values[id]; exists {
+		return false
+	}
+
+	if rd.allowFunc != nil && !rd.allowFunc(id) {
+		rd.emit(EventRejected, id)
+		return false
+	}
+
+	rd.values[id] = rd.order.PushBack(&evictingSetEntry[T]{value: id, added: now})
+	rd.emit(EventAdded, id)
 	rd.evictItems()
 
-	rd.mu.Unlock()
+	return true
 }
 
 func (rd *EvictingSet[T]) Remove(item T) {
 	rd.mu.Lock()
-	delete(rd.values, item)
 
-	rd.order = slices.DeleteFunc(rd.order, func(v T) bool {
-		return v == item
-	})
+	if elem, ok := rd.values[item]; ok {
+		rd.order.Remove(elem)
+		delete(rd.values, item)
+		rd.emit(EventRemoved, item)
+	}
 
-	rd.mu.Unlock()
+	rd.unlock()
 }
 
 func (rd *EvictingSet[T]) Items() []T {
-	rd.mu.RLock()
-	defer rd.mu.RUnlock()
+	rd.mu.Lock()
+	defer rd.unlock()
 
-	result := make([]T, 0, len(rd.order))
-	for _, key := range rd.order {
-		if _, ok := rd.values[key]; ok {
-			result = append(result, key)
-		}
+	rd.expireItems(time.Now())
+
+	result := make([]T, 0, rd.order.Len())
+	for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+		result = append(result, elem.Value.(*evictingSetEntry[T]).value)
 	}
 	return result
 }
 
 func (rd *EvictingSet[T]) Clear() {
 	rd.mu.Lock()
-	defer rd.mu.Unlock()
+	defer rd.unlock()
 
-	rd.values = make(map[T]struct{})
-	rd.order = rd.order[:0]
+	if rd.Events != nil {
+		for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+			rd.emit(EventRemoved, elem.Value.(*evictingSetEntry[T]).value)
+		}
+	}
+
+	rd.values = make(map[T]*list.Element)
+	rd.order.Init()
 }
 
 func (rd *EvictingSet[T]) Exists(items ...T) bool {
+	now := time.Now()
+
 	rd.mu.RLock()
 	exists := slices.ContainsFunc(items, func(item T) bool {
-		_, ok := rd.values[item]
-		return ok
+		elem, ok := rd.values[item]
+		return ok && !rd.expired(elem.Value.(*evictingSetEntry[T]), now)
 	})
 	rd.mu.RUnlock()
 	return exists
@@ -84,32 +149,178 @@ func (rd *EvictingSet[T]) AllowFunc(fn func(T T) bool) *EvictingSet[T] {
 }
 
 func (rd *EvictingSet[T]) Len() int {
-	rd.mu.RLock()
+	rd.mu.Lock()
+	rd.expireItems(time.Now())
 	length := len(rd.values)
-	rd.mu.RUnlock()
+	rd.unlock()
 	return length
 }
 
 func (rd *EvictingSet[T]) SetMax(max int) *EvictingSet[T] {
 	rd.mu.Lock()
 	if rd.max == max {
-		rd.mu.Unlock()
+		rd.unlock()
 		return rd
 	}
 
 	rd.max = max
 	rd.evictItems()
-	rd.mu.Unlock()
+	rd.unlock()
+
+	return rd
+}
+
+func (rd *EvictingSet[T]) SetTTL(ttl time.Duration) *EvictingSet[T] {
+	rd.mu.Lock()
+	rd.ttl = ttl
+	rd.expireItems(time.Now())
+	rd.unlock()
 
 	return rd
 }
 
+func (rd *EvictingSet[T]) expired(entry *evictingSetEntry[T], now time.Time) bool {
+	return rd.ttl > 0 && now.Sub(entry.added) >= rd.ttl
+}
+
+func (rd *EvictingSet[T]) expireItems(now time.Time) {
+	if rd.ttl <= 0 {
+		return
+	}
+
+	for front := rd.order.Front(); front != nil; front = rd.order.Front() {
+		entry := front.Value.(*evictingSetEntry[T])
+		if !rd.expired(entry, now) {
+			return
+		}
+
+		rd.order.Remove(front)
+		delete(rd.values, entry.value)
+		rd.emit(EventEvicted, entry.value)
+	}
+}
+
 func (rd *EvictingSet[T]) evictItems() {
-	for len(rd.values) > rd.max && len(rd.order) > 0 {
-		evictKey := rd.order[0]
-		rd.order = rd.order[1:]
-		delete(rd.values, evictKey)
+	for rd.max >= 0 && len(rd.values) > rd.max {
+		front := rd.order.Front()
+		if front == nil {
+			return
+		}
+
+		value := front.Value.(*evictingSetEntry[T]).value
+		rd.order.Remove(front)
+		delete(rd.values, value)
+		rd.emit(EventEvicted, value)
+	}
+}
+
+func (rd *EvictingSet[T]) emit(event evictingEventType, value T) {
+	if rd.Events != nil {
+		rd.pending = append(rd.pending, EvictingEvent[T, struct{}]{Type: event, Key: value})
+	}
+}
+
+func (rd *EvictingSet[T]) unlock() {
+	pending := rd.pending
+	rd.pending = nil
+	if len(pending) == 0 {
+		rd.mu.Unlock()
+		return
+	}
+
+	rd.emitMu.Lock()
+	rd.mu.Unlock()
+	for _, event := range pending {
+		rd.Events.Broadcast(event)
+	}
+	rd.emitMu.Unlock()
+}
+
+func (rd *EvictingSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rd.state())
+}
+
+func (rd *EvictingSet[T]) UnmarshalJSON(data []byte) error {
+	var state evictingSetState[T]
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	rd.restore(state)
+	return nil
+}
+
+func (rd *EvictingSet[T]) MarshalBinary() ([]byte, error) {
+	return marshalEvicting(evictingSetMagic, evictingSetVersion, rd.state())
+}
+
+func (rd *EvictingSet[T]) UnmarshalBinary(data []byte) error {
+	var state evictingSetState[T]
+	if err := unmarshalEvicting(evictingSetMagic, evictingSetVersion, data, &state); err != nil {
+		return err
+	}
+
+	rd.restore(state)
+	return nil
+}
+
+func (rd *EvictingSet[T]) state() evictingSetState[T] {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	state := evictingSetState[T]{Max: rd.max, TTL: rd.ttl, Items: make([]evictingSetItem[T], 0, len(rd.values))}
+	if rd.order == nil {
+		return state
+	}
+
+	for elem := rd.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*evictingSetEntry[T])
+		state.Items = append(state.Items, evictingSetItem[T]{Value: entry.value, Added: entry.added})
+	}
+
+	return state
+}
+
+func (rd *EvictingSet[T]) restore(state evictingSetState[T]) {
+	rd.mu.Lock()
+	defer rd.unlock()
+
+	rd.values = make(map[T]*list.Element, len(state.Items))
+	rd.order = list.New()
+	rd.max = state.Max
+	rd.ttl = state.TTL
+
+	for _, item := range state.Items {
+		if elem, exists := rd.values[item.Value]; exists {
+			rd.order.Remove(elem)
+		}
+		rd.values[item.Value] = rd.order.PushBack(&evictingSetEntry[T]{value: item.Value, added: item.Added})
+	}
+
+	rd.expireItems(time.Now())
+	rd.evictItems()
+}
+
+func marshalEvicting(magic string, version byte, state any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	buf.WriteByte(version)
+
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unmarshalEvicting(magic string, version byte, data []byte, state any) error {
+	if len(data) <= len(magic) || string(data[:len(magic)]) != magic {
+		return ErrEvictingFormat
+	} else if got := data[len(magic)]; got != version {
+		return fmt.Errorf("%w: %d (want %d)", ErrEvictingVersion, got, version)
 	}
+
+	return gob.NewDecoder(bytes.NewReader(data[len(magic)+1:])).Decode(state)
 }
 
 func (d *EvictingSet[T]) WithBroadcaster() *EvictingSet[T] {
@@ -117,6 +328,20 @@ func (d *EvictingSet[T]) WithBroadcaster() *EvictingSet[T] {
 	return d
 }
 
+func (d *EvictingSet[T]) WithEvents() *EvictingSet[T] {
+	d.Events = NewBroadcaster[EvictingEvent[T, struct{}]]()
+	return d
+}
+
 func NewEvictingSet[T comparable](max int) *EvictingSet[T] {
-	return &EvictingSet[T]{values: map[T]struct{}{}, max: max}
+	return &EvictingSet[T]{values: map[T]*list.Element{}, order: list.New(), max: max}
+}
+
+func NewTTLSet[T comparable](ttl time.Duration, max ...int) *EvictingSet[T] {
+	set := NewEvictingSet[T](-1)
+	if len(max) > 0 {
+		set.max = max[0]
+	}
+	set.ttl = ttl
+	return set
 }
@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+const (
+	HubSeparator      = "."
+	HubWildcard       = "*"
+	HubFullWildcard   = ">"
+	hubWildcardTokens = HubWildcard + HubFullWildcard
+)
+
+var (
+	ErrHubClosed    = errors.New("hub closed")
+	ErrInvalidTopic = errors.New("invalid topic")
+)
+
+type HubMessage[T any] struct {
+	Topic string `json:"topic"`
+	Value T      `json:"value"`
+}
+
+type hubPattern[T any] struct {
+	tokens      []string
+	broadcaster *Broadcaster[HubMessage[T]]
+}
+
+type Hub[T any] struct {
+	patterns    map[string]*hubPattern[T]
+	subscribers map[chan HubMessage[T]]string
+	closed      bool
+	mu          sync.RWMutex
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		patterns:    map[string]*hubPattern[T]{},
+		subscribers: map[chan HubMessage[T]]string{},
+	}
+}
+
+func (h *Hub[T]) Subscribe(pattern string, options ...SubscribeOption) (chan HubMessage[T], error) {
+	return h.subscribe(pattern, nil, options)
+}
+
+func (h *Hub[T]) SubscribeCtx(ctx context.Context, pattern string, options ...SubscribeOption) (chan HubMessage[T], error) {
+	return h.subscribe(pattern, ctx.Done(), options)
+}
+
+func (h *Hub[T]) SubscribeContext(ctx *Context, pattern string, options ...SubscribeOption) (chan HubMessage[T], error) {
+	return h.subscribe(pattern, ctx.C(), options)
+}
+
+func (h *Hub[T]) subscribe(pattern string, done <-chan struct{}, options []SubscribeOption) (chan HubMessage[T], error) {
+	tokens, ok := parseTopic(pattern, true)
+	if !ok {
+		return nil, ErrInvalidTopic
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+
+	entry, exists := h.patterns[pattern]
+	if !exists {
+		entry = &hubPattern[T]{tokens: tokens, broadcaster: NewBroadcaster[HubMessage[T]]()}
+		h.patterns[pattern] = entry
+	}
+
+	sub := entry.broadcaster.subscribe(options)
+	h.subscribers[sub.ch] = pattern
+	h.mu.Unlock()
+
+	// The broadcaster closes sub.done however the subscription ends (Unsubscribe,
+	// a disconnected slow consumer or Close), so bookkeeping is dropped here.
+	go func() {
+		select {
+		case <-done:
+			entry.broadcaster.Unsubscribe(sub.ch)
+		case <-sub.done:
+		}
+		h.release(sub.ch)
+	}()
+
+	return sub.ch, nil
+}
+
+func (h *Hub[T]) release(ch chan HubMessage[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pattern, exists := h.subscribers[ch]
+	if !exists {
+		return
+	}
+
+	delete(h.subscribers, ch)
+	if entry := h.patterns[pattern]; entry != nil && entry.broadcaster.Len() == 0 {
+		delete(h.patterns, pattern)
+	}
+}
+
+func (h *Hub[T]) Unsubscribe(ch chan HubMessage[T]) {
+	h.mu.RLock()
+	pattern, exists := h.subscribers[ch]
+	entry := h.patterns[pattern]
+	h.mu.RUnlock()
+
+	if exists && entry != nil {
+		entry.broadcaster.Unsubscribe(ch)
+	}
+}
+
+func (h *Hub[T]) Publish(topic string, value T) error {
+	tokens, ok := parseTopic(topic, false)
+	if !ok {
+		return ErrInvalidTopic
+	}
+
+	msg := HubMessage[T]{Topic: topic, Value: value}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	for _, entry := range h.patterns {
+		if matchTopic(entry.tokens, tokens) {
+			entry.broadcaster.Broadcast(msg)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub[T]) Subscribers(topic string) int {
+	tokens, ok := parseTopic(topic, false)
+	if !ok {
+		return 0
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, entry := range h.patterns {
+		if matchTopic(entry.tokens, tokens) {
+			count += entry.broadcaster.Len()
+		}
+	}
+
+	return count
+}
+
+func (h *Hub[T]) Patterns() map[string]int {
+	h.mu.RLock()
+	patterns := make(map[string]int, len(h.patterns))
+	for pattern, entry := range h.patterns {
+		patterns[pattern] = entry.broadcaster.Len()
+	}
+	h.mu.RUnlock()
+	return patterns
+}
+
+func (h *Hub[T]) Len() int {
+	h.mu.RLock()
+	length := len(h.subscribers)
+	h.mu.RUnlock()
+	return length
+}
+
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+
+	h.closed = true
+	patterns := h.patterns
+	h.patterns = map[string]*hubPattern[T]{}
+	h.mu.Unlock()
+
+	for _, entry := range patterns {
+		entry.broadcaster.Close()
+	}
+}
+
+func (h *Hub[T]) Closed() bool {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+	return closed
+}
+
+func parseTopic(topic string, wildcards bool) ([]string, bool) {
+	if topic == "" {
+		return nil, false
+	}
+
+	tokens := strings.Split(topic, HubSeparator)
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return nil, false
+		case token == HubWildcard || token == HubFullWildcard:
+			if !wildcards || (token == HubFullWildcard && i != len(tokens)-1) {
+				return nil, false
+			}
+		case strings.ContainsAny(token, hubWildcardTokens):
+			return nil, false
+		}
+	}
+
+	return tokens, true
+}
+
+func matchTopic(pattern, topic []string) bool {
+	for i, token := range pattern {
+		if token == HubFullWildcard {
+			return len(topic) > i
+		} else if i >= len(topic) || (token != HubWildcard && token != topic[i]) {
+			return false
+		}
+	}
+
+	return len(pattern) == len(topic)
+}
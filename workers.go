@@ -1,21 +1,45 @@
 package utils
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("worker closed")
 
 type Worker[T any] struct {
 	ch   chan T
 	fn   func(T)
+	done chan struct{}
 	once sync.Once
+	mu   sync.RWMutex
 	wg   sync.WaitGroup
 }
 
 func (w *Worker[T]) Close() {
 	w.once.Do(func() {
+		// Closing done first releases blocked senders so the write lock can be
+		// taken; once it is held no sender can be between its check and ch.
+		close(w.done)
+
+		w.mu.Lock()
 		close(w.ch)
+		w.mu.Unlock()
+
 		w.wg.Wait()
 	})
 }
 
+func (w *Worker[T]) Closed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (w *Worker[T]) Worker() {
 	defer w.wg.Done()
 
@@ -24,8 +48,42 @@ func (w *Worker[T]) Worker() {
 	}
 }
 
-func (w *Worker[T]) Send(v T) {
-	w.ch <- v
+func (w *Worker[T]) Send(v T) error {
+	return w.SendCtx(context.Background(), v)
+}
+
+func (w *Worker[T]) SendCtx(ctx context.Context, v T) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.Closed() {
+		return ErrClosed
+	}
+
+	select {
+	case w.ch <- v:
+		return nil
+	case <-w.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Worker[T]) TrySend(v T) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.Closed() {
+		return false
+	}
+
+	select {
+	case w.ch <- v:
+		return true
+	default:
+		return false
+	}
 }
 
 func NewWorker[T any](workers, buf int, fn func(T)) *Worker[T] {
@@ -38,8 +96,9 @@ func NewWorker[T any](workers, buf int, fn func(T)) *Worker[T] {
 	ch := make(chan T, buf)
 
 	handler := Worker[T]{
-		ch: ch,
-		fn: fn,
+		ch:   ch,
+		fn:   fn,
+		done: make(chan struct{}),
 	}
 
 	handler.wg.Add(workers)